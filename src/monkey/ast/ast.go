@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
 	"monkey/token"
 	"strings"
 )
@@ -10,6 +11,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the first character belonging to the node
+	End() token.Position // position immediately after the node
 }
 
 // Statement is an AST node representing a statement
@@ -38,6 +41,22 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+// Pos for Program
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+// End for Program
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 // String representation of Program
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -51,9 +70,10 @@ func (p *Program) String() string {
 
 // LetStatement is an AST node representing a let statement
 type LetStatement struct {
-	Token token.Token // the token.LET token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // the token.LET token
+	Name    *Identifier
+	Pattern Expression // an array or hash pattern, set instead of Name when destructuring
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -63,12 +83,35 @@ func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+// Pos for LetStatement
+func (ls *LetStatement) Pos() token.Position {
+	return ls.Token.Pos
+}
+
+// End for LetStatement
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Pattern != nil {
+		return ls.Pattern.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End
+}
+
 // String representation of LetStatement
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -98,6 +141,16 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+// Pos for Identifier
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
+
+// End for Identifier
+func (i *Identifier) End() token.Position {
+	return i.Token.End
+}
+
 // ReturnStatement is an AST node representing a return statement
 type ReturnStatement struct {
 	Token       token.Token
@@ -111,6 +164,19 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+// Pos for ReturnStatement
+func (rs *ReturnStatement) Pos() token.Position {
+	return rs.Token.Pos
+}
+
+// End for ReturnStatement
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
+
 // String representation of ReturnStatement
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -126,6 +192,183 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// WhileStatement runs Body for as long as Condition is truthy
+type WhileStatement struct {
+	Token     token.Token // the 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+
+// TokenLiteral for WhileStatement
+func (ws *WhileStatement) TokenLiteral() string {
+	return ws.Token.Literal
+}
+
+// Pos for WhileStatement
+func (ws *WhileStatement) Pos() token.Position {
+	return ws.Token.Pos
+}
+
+// End for WhileStatement
+func (ws *WhileStatement) End() token.Position {
+	if ws.Body != nil {
+		return ws.Body.End()
+	}
+	return ws.Token.End
+}
+
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while ")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// ForStatement runs Body once for each item of Iterable. Key is the optional
+// first of two loop variables, bound to the index of an array or string
+// element or to the key of a hash entry. Value is bound to the element, or
+// to the key when looping over a hash with a single variable.
+type ForStatement struct {
+	Token    token.Token // the 'for' token
+	Key      *Identifier
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+
+// TokenLiteral for ForStatement
+func (fs *ForStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+// Pos for ForStatement
+func (fs *ForStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
+// End for ForStatement
+func (fs *ForStatement) End() token.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+	return fs.Token.End
+}
+
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String() + ", ")
+	}
+	out.WriteString(fs.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// BreakStatement ends the innermost loop
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+// TokenLiteral for BreakStatement
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+
+// Pos for BreakStatement
+func (bs *BreakStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+// End for BreakStatement
+func (bs *BreakStatement) End() token.Position {
+	return bs.Token.End
+}
+
+func (bs *BreakStatement) String() string {
+	return bs.Token.Literal + ";"
+}
+
+// ContinueStatement skips to the next iteration of the innermost loop
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+// TokenLiteral for ContinueStatement
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+// Pos for ContinueStatement
+func (cs *ContinueStatement) Pos() token.Position {
+	return cs.Token.Pos
+}
+
+// End for ContinueStatement
+func (cs *ContinueStatement) End() token.Position {
+	return cs.Token.End
+}
+
+func (cs *ContinueStatement) String() string {
+	return cs.Token.Literal + ";"
+}
+
+// ThrowStatement raises Value as an error, which the innermost enclosing try
+// can catch
+type ThrowStatement struct {
+	Token token.Token // the 'throw' token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+// TokenLiteral for ThrowStatement
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+// Pos for ThrowStatement
+func (ts *ThrowStatement) Pos() token.Position {
+	return ts.Token.Pos
+}
+
+// End for ThrowStatement
+func (ts *ThrowStatement) End() token.Position {
+	if ts.Value != nil {
+		return ts.Value.End()
+	}
+	return ts.Token.End
+}
+
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ts.TokenLiteral() + " ")
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
 // ExpressionStatement is an AST node representing an expression statement.
 // This exists so we can add an expression to the list of statements in our // program. ie. `5 + 5` on a line by itself is valid in Monkey
 type ExpressionStatement struct {
@@ -140,6 +383,19 @@ func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+// Pos for ExpressionStatement
+func (es *ExpressionStatement) Pos() token.Position {
+	return es.Token.Pos
+}
+
+// End for ExpressionStatement
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
+
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -161,10 +417,75 @@ func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
 
+// Pos for IntegerLiteral
+func (il *IntegerLiteral) Pos() token.Position {
+	return il.Token.Pos
+}
+
+// End for IntegerLiteral
+func (il *IntegerLiteral) End() token.Position {
+	return il.Token.End
+}
+
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+// BigIntegerLiteral is an expression representing an integer too large to
+// fit in an IntegerLiteral
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (bl *BigIntegerLiteral) expressionNode() {}
+
+// TokenLiteral for BigIntegerLiteral
+func (bl *BigIntegerLiteral) TokenLiteral() string {
+	return bl.Token.Literal
+}
+
+// Pos for BigIntegerLiteral
+func (bl *BigIntegerLiteral) Pos() token.Position {
+	return bl.Token.Pos
+}
+
+// End for BigIntegerLiteral
+func (bl *BigIntegerLiteral) End() token.Position {
+	return bl.Token.End
+}
+
+func (bl *BigIntegerLiteral) String() string {
+	return bl.Token.Literal
+}
+
+// FloatLiteral is an expression representing a floating point number
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+// TokenLiteral for FloatLiteral
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+// Pos for FloatLiteral
+func (fl *FloatLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
+// End for FloatLiteral
+func (fl *FloatLiteral) End() token.Position {
+	return fl.Token.End
+}
+
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
 // PrefixExpression is an expression before another expression, such as -1 or !true
 type PrefixExpression struct {
 	Token    token.Token //the prefix token, eg !
@@ -179,6 +500,19 @@ func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+// Pos for PrefixExpression
+func (pe *PrefixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
+
+// End for PrefixExpression
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
+
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -204,6 +538,22 @@ func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+// Pos for InfixExpression
+func (ie *InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+
+// End for InfixExpression
+func (ie *InfixExpression) End() token.Position {
+	if ie.Right != nil {
+		return ie.Right.End()
+	}
+	return ie.Token.End
+}
+
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -229,10 +579,64 @@ func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+// Pos for Boolean
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
+
+// End for Boolean
+func (b *Boolean) End() token.Position {
+	return b.Token.End
+}
+
 func (b *Boolean) String() string {
 	return b.Token.Literal
 }
 
+// AssignExpression represents an assignment to an existing variable, array
+// element or hash entry, either plain like x = 5 or compound like x += 5
+type AssignExpression struct {
+	Token    token.Token // the assignment operator token, eg +=
+	Target   Expression  // an *Identifier or an *IndexExpression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+
+// TokenLiteral for AssignExpression
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+// Pos for AssignExpression
+func (ae *AssignExpression) Pos() token.Position {
+	if ae.Target != nil {
+		return ae.Target.Pos()
+	}
+	return ae.Token.Pos
+}
+
+// End for AssignExpression
+func (ae *AssignExpression) End() token.Position {
+	if ae.Value != nil {
+		return ae.Value.End()
+	}
+	return ae.Token.End
+}
+
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // IfExpression represents an if expression
 type IfExpression struct {
 	Token       token.Token // The 'if' token
@@ -248,6 +652,22 @@ func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+// Pos for IfExpression
+func (ie *IfExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
+// End for IfExpression
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return ie.Token.End
+}
+
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -264,10 +684,155 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// MatchExpression compares Subject against the pattern of each arm in turn,
+// and evaluates the body of the first arm that matches
+type MatchExpression struct {
+	Token   token.Token // the 'match' token
+	Subject Expression
+	Arms    []*MatchArm
+	Rbrace  token.Token // the closing '}' token
+}
+
+func (me *MatchExpression) expressionNode() {}
+
+// TokenLiteral for MatchExpression
+func (me *MatchExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+// Pos for MatchExpression
+func (me *MatchExpression) Pos() token.Position {
+	return me.Token.Pos
+}
+
+// End for MatchExpression
+func (me *MatchExpression) End() token.Position {
+	if me.Rbrace.End.IsValid() {
+		return me.Rbrace.End
+	}
+	return me.Token.End
+}
+
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match ")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// MatchArm is one arm of a match expression. Pattern is made of literals,
+// identifiers that bind the matched value, the wildcard _, and array or hash
+// literals of patterns. An array pattern may end in a ...rest identifier.
+// The arm only matches if its optional Guard is truthy as well.
+type MatchArm struct {
+	Token   token.Token // the first token of the pattern
+	Pattern Expression
+	Guard   Expression
+	Body    Node // an Expression, or a *BlockStatement
+}
+
+// TokenLiteral for MatchArm
+func (ma *MatchArm) TokenLiteral() string {
+	return ma.Token.Literal
+}
+
+// Pos for MatchArm
+func (ma *MatchArm) Pos() token.Position {
+	return ma.Token.Pos
+}
+
+// End for MatchArm
+func (ma *MatchArm) End() token.Position {
+	if ma.Body != nil {
+		return ma.Body.End()
+	}
+	return ma.Token.End
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// TryExpression evaluates Block, and if that fails with an error, evaluates
+// Catch with the error bound to Param. Finally is evaluated last in any case.
+// At least one of Catch and Finally is set.
+type TryExpression struct {
+	Token   token.Token // the 'try' token
+	Block   *BlockStatement
+	Param   *Identifier
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+
+// TokenLiteral for TryExpression
+func (te *TryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+
+// Pos for TryExpression
+func (te *TryExpression) Pos() token.Position {
+	return te.Token.Pos
+}
+
+// End for TryExpression
+func (te *TryExpression) End() token.Position {
+	switch {
+	case te.Finally != nil:
+		return te.Finally.End()
+	case te.Catch != nil:
+		return te.Catch.End()
+	case te.Block != nil:
+		return te.Block.End()
+	}
+	return te.Token.End
+}
+
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch (" + te.Param.String() + ") ")
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
+
 // BlockStatement represents a block containing statements
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
+	Rbrace     token.Token // the closing } token
 }
 
 func (bs *BlockStatement) statementNode() {}
@@ -277,6 +842,22 @@ func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
 
+// Pos for BlockStatement
+func (bs *BlockStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+// End for BlockStatement
+func (bs *BlockStatement) End() token.Position {
+	if bs.Rbrace.End.IsValid() {
+		return bs.Rbrace.End
+	}
+	if len(bs.Statements) > 0 {
+		return bs.Statements[len(bs.Statements)-1].End()
+	}
+	return bs.Token.End
+}
+
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -290,7 +871,7 @@ func (bs *BlockStatement) String() string {
 // FunctionLiteral represents a function literal
 type FunctionLiteral struct {
 	Token      token.Token //the fn token
-	Parameters []*Identifier
+	Parameters []*Parameter
 	Body       *BlockStatement
 }
 
@@ -301,6 +882,19 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
 
+// Pos for FunctionLiteral
+func (fl *FunctionLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
+// End for FunctionLiteral
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
+
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -318,11 +912,104 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// MacroLiteral represents a macro, like macro(a, b) { quote(unquote(a) + b) }.
+// Its parameters are bound to the quoted arguments of a macro call.
+type MacroLiteral struct {
+	Token      token.Token // the 'macro' token
+	Parameters []*Parameter
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode() {}
+
+// TokenLiteral for MacroLiteral
+func (ml *MacroLiteral) TokenLiteral() string {
+	return ml.Token.Literal
+}
+
+// Pos for MacroLiteral
+func (ml *MacroLiteral) Pos() token.Position {
+	return ml.Token.Pos
+}
+
+// End for MacroLiteral
+func (ml *MacroLiteral) End() token.Position {
+	if ml.Body != nil {
+		return ml.Body.End()
+	}
+	return ml.Token.End
+}
+
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+
+	return out.String()
+}
+
+// Parameter is a parameter of a function literal. It either has an optional
+// default value, or is the rest parameter collecting any extra arguments.
+type Parameter struct {
+	Token   token.Token // the parameter name, the '...' token or the start of a pattern
+	Name    *Identifier
+	Pattern Expression // an array or hash pattern, set instead of Name when destructuring
+	Default Expression
+	Rest    bool
+}
+
+// TokenLiteral for Parameter
+func (p *Parameter) TokenLiteral() string {
+	return p.Token.Literal
+}
+
+// Pos for Parameter
+func (p *Parameter) Pos() token.Position {
+	return p.Token.Pos
+}
+
+// End for Parameter
+func (p *Parameter) End() token.Position {
+	if p.Default != nil {
+		return p.Default.End()
+	}
+	return p.Target().End()
+}
+
+// Target returns the name or the pattern the parameter binds
+func (p *Parameter) Target() Expression {
+	if p.Pattern != nil {
+		return p.Pattern
+	}
+	return p.Name
+}
+
+func (p *Parameter) String() string {
+	switch {
+	case p.Rest:
+		return "..." + p.Target().String()
+	case p.Default != nil:
+		return p.Target().String() + " = " + p.Default.String()
+	default:
+		return p.Target().String()
+	}
+}
+
 // CallExpression represents the calling of a function
 type CallExpression struct {
 	Token     token.Token // the '(' token
 	Function  Expression
 	Arguments []Expression
+	Rparen    token.Token // the closing ')' token
 }
 
 func (ce *CallExpression) expressionNode() {}
@@ -332,6 +1019,22 @@ func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+// Pos for CallExpression
+func (ce *CallExpression) Pos() token.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos
+}
+
+// End for CallExpression
+func (ce *CallExpression) End() token.Position {
+	if ce.Rparen.End.IsValid() {
+		return ce.Rparen.End
+	}
+	return ce.Token.End
+}
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -361,6 +1064,16 @@ func (sl *StringLiteral) TokenLiteral() string {
 	return sl.Token.Literal
 }
 
+// Pos for StringLiteral
+func (sl *StringLiteral) Pos() token.Position {
+	return sl.Token.Pos
+}
+
+// End for StringLiteral
+func (sl *StringLiteral) End() token.Position {
+	return sl.Token.End
+}
+
 func (sl *StringLiteral) String() string {
 	return sl.Token.Literal
 }
@@ -369,6 +1082,7 @@ func (sl *StringLiteral) String() string {
 type ArrayLiteral struct {
 	Token    token.Token // the '[' character
 	Elements []Expression
+	Rbracket token.Token // the closing ']' token
 }
 
 func (al *ArrayLiteral) expressionNode() {}
@@ -378,6 +1092,19 @@ func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Literal
 }
 
+// Pos for ArrayLiteral
+func (al *ArrayLiteral) Pos() token.Position {
+	return al.Token.Pos
+}
+
+// End for ArrayLiteral
+func (al *ArrayLiteral) End() token.Position {
+	if al.Rbracket.End.IsValid() {
+		return al.Rbracket.End
+	}
+	return al.Token.End
+}
+
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -395,9 +1122,10 @@ func (al *ArrayLiteral) String() string {
 
 // IndexExpression represents referencing an array element by index, like myArr[2]
 type IndexExpression struct {
-	Token token.Token // the '[' token
-	Left  Expression
-	Index Expression
+	Token    token.Token // the '[' token
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token // the closing ']' token
 }
 
 func (ie *IndexExpression) expressionNode() {}
@@ -407,6 +1135,22 @@ func (ie *IndexExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+// Pos for IndexExpression
+func (ie *IndexExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+
+// End for IndexExpression
+func (ie *IndexExpression) End() token.Position {
+	if ie.Rbracket.End.IsValid() {
+		return ie.Rbracket.End
+	}
+	return ie.Token.End
+}
+
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -421,8 +1165,25 @@ func (ie *IndexExpression) String() string {
 
 // HashLiteral represents a hash, like { "foo": "bar "}
 type HashLiteral struct {
-	Token token.Token // the '{' token
-	Pairs map[Expression]Expression
+	Token  token.Token // the '{' token
+	Pairs  map[Expression]Expression
+	Order  []Expression // the keys of Pairs and any spreads, in source order
+	Rbrace token.Token  // the closing '}' token
+}
+
+// Entries returns the keys of the pairs and the spreads of the literal, in
+// source order. A literal built without Order falls back to the keys of
+// Pairs, in no particular order.
+func (hl *HashLiteral) Entries() []Expression {
+	if hl.Order != nil {
+		return hl.Order
+	}
+
+	entries := []Expression{}
+	for key := range hl.Pairs {
+		entries = append(entries, key)
+	}
+	return entries
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -432,12 +1193,29 @@ func (hl *HashLiteral) TokenLiteral() string {
 	return hl.Token.Literal
 }
 
+// Pos for HashLiteral
+func (hl *HashLiteral) Pos() token.Position {
+	return hl.Token.Pos
+}
+
+// End for HashLiteral
+func (hl *HashLiteral) End() token.Position {
+	if hl.Rbrace.End.IsValid() {
+		return hl.Rbrace.End
+	}
+	return hl.Token.End
+}
+
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, entry := range hl.Entries() {
+		if spread, ok := entry.(*SpreadExpression); ok {
+			pairs = append(pairs, spread.String())
+			continue
+		}
+		pairs = append(pairs, entry.String()+":"+hl.Pairs[entry].String())
 	}
 
 	out.WriteString("{")
@@ -446,3 +1224,87 @@ func (hl *HashLiteral) String() string {
 
 	return out.String()
 }
+
+// SpreadExpression expands an array into the arguments of a call or the
+// elements of an array literal, or a hash into the pairs of a hash literal
+type SpreadExpression struct {
+	Token token.Token // the '...' token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode() {}
+
+// TokenLiteral for SpreadExpression
+func (se *SpreadExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+// Pos for SpreadExpression
+func (se *SpreadExpression) Pos() token.Position {
+	return se.Token.Pos
+}
+
+// End for SpreadExpression
+func (se *SpreadExpression) End() token.Position {
+	return se.Value.End()
+}
+
+func (se *SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
+// BadStatement is a placeholder for a statement that could not be parsed
+type BadStatement struct {
+	Token token.Token // the first token of the bad statement
+	To    token.Token // the last token skipped while recovering
+}
+
+func (bs *BadStatement) statementNode() {}
+
+// TokenLiteral for BadStatement
+func (bs *BadStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+
+// Pos for BadStatement
+func (bs *BadStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+// End for BadStatement
+func (bs *BadStatement) End() token.Position {
+	if bs.To.End.IsValid() {
+		return bs.To.End
+	}
+	return bs.Token.End
+}
+
+func (bs *BadStatement) String() string {
+	return "<bad statement>"
+}
+
+// BadExpression is a placeholder for an expression that could not be parsed
+type BadExpression struct {
+	Token token.Token // the token where parsing the expression failed
+}
+
+func (be *BadExpression) expressionNode() {}
+
+// TokenLiteral for BadExpression
+func (be *BadExpression) TokenLiteral() string {
+	return be.Token.Literal
+}
+
+// Pos for BadExpression
+func (be *BadExpression) Pos() token.Position {
+	return be.Token.Pos
+}
+
+// End for BadExpression
+func (be *BadExpression) End() token.Position {
+	return be.Token.End
+}
+
+func (be *BadExpression) String() string {
+	return "<bad expression>"
+}
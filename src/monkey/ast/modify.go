@@ -0,0 +1,231 @@
+package ast
+
+// ModifierFunc is called by Modify on every node of a tree, and returns the
+// node to put in its place
+type ModifierFunc func(Node) Node
+
+// Modify walks the tree rooted at node depth first, and returns a copy of it
+// in which every node has been replaced by the result of calling modifier on
+// it. The children of a node are replaced before the node itself, including
+// the names and patterns that a let, a parameter, a for loop, a match arm or
+// a catch binds. A replacement that does not fit where the original node was,
+// such as a statement in place of an expression, is ignored. The tree passed
+// in is left unchanged.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		program := *node
+		program.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&program)
+
+	case *BlockStatement:
+		block := *node
+		block.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&block)
+
+	case *ExpressionStatement:
+		stmt := *node
+		stmt.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&stmt)
+
+	case *LetStatement:
+		stmt := *node
+		stmt.Name = modifyIdentifier(node.Name, modifier)
+		stmt.Pattern = modifyExpression(node.Pattern, modifier)
+		stmt.Value = modifyExpression(node.Value, modifier)
+		return modifier(&stmt)
+
+	case *ReturnStatement:
+		stmt := *node
+		stmt.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		return modifier(&stmt)
+
+	case *ThrowStatement:
+		stmt := *node
+		stmt.Value = modifyExpression(node.Value, modifier)
+		return modifier(&stmt)
+
+	case *WhileStatement:
+		stmt := *node
+		stmt.Condition = modifyExpression(node.Condition, modifier)
+		stmt.Body = modifyBlock(node.Body, modifier)
+		return modifier(&stmt)
+
+	case *ForStatement:
+		stmt := *node
+		stmt.Key = modifyIdentifier(node.Key, modifier)
+		stmt.Value = modifyIdentifier(node.Value, modifier)
+		stmt.Iterable = modifyExpression(node.Iterable, modifier)
+		stmt.Body = modifyBlock(node.Body, modifier)
+		return modifier(&stmt)
+
+	case *PrefixExpression:
+		exp := *node
+		exp.Right = modifyExpression(node.Right, modifier)
+		return modifier(&exp)
+
+	case *InfixExpression:
+		exp := *node
+		exp.Left = modifyExpression(node.Left, modifier)
+		exp.Right = modifyExpression(node.Right, modifier)
+		return modifier(&exp)
+
+	case *AssignExpression:
+		exp := *node
+		exp.Target = modifyExpression(node.Target, modifier)
+		exp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&exp)
+
+	case *IndexExpression:
+		exp := *node
+		exp.Left = modifyExpression(node.Left, modifier)
+		exp.Index = modifyExpression(node.Index, modifier)
+		return modifier(&exp)
+
+	case *IfExpression:
+		exp := *node
+		exp.Condition = modifyExpression(node.Condition, modifier)
+		exp.Consequence = modifyBlock(node.Consequence, modifier)
+		exp.Alternative = modifyBlock(node.Alternative, modifier)
+		return modifier(&exp)
+
+	case *MatchExpression:
+		exp := *node
+		exp.Subject = modifyExpression(node.Subject, modifier)
+		exp.Arms = make([]*MatchArm, len(node.Arms))
+		for i, arm := range node.Arms {
+			modified := *arm
+			modified.Pattern = modifyExpression(arm.Pattern, modifier)
+			modified.Guard = modifyExpression(arm.Guard, modifier)
+			switch body := arm.Body.(type) {
+			case *BlockStatement:
+				modified.Body = modifyBlock(body, modifier)
+			case Expression:
+				modified.Body = modifyExpression(body, modifier)
+			}
+			exp.Arms[i] = &modified
+		}
+		return modifier(&exp)
+
+	case *TryExpression:
+		exp := *node
+		exp.Block = modifyBlock(node.Block, modifier)
+		exp.Param = modifyIdentifier(node.Param, modifier)
+		exp.Catch = modifyBlock(node.Catch, modifier)
+		exp.Finally = modifyBlock(node.Finally, modifier)
+		return modifier(&exp)
+
+	case *FunctionLiteral:
+		fn := *node
+		fn.Parameters = make([]*Parameter, len(node.Parameters))
+		for i, param := range node.Parameters {
+			modified := *param
+			modified.Name = modifyIdentifier(param.Name, modifier)
+			modified.Pattern = modifyExpression(param.Pattern, modifier)
+			modified.Default = modifyExpression(param.Default, modifier)
+			fn.Parameters[i] = &modified
+		}
+		fn.Body = modifyBlock(node.Body, modifier)
+		return modifier(&fn)
+
+	case *CallExpression:
+		exp := *node
+		exp.Function = modifyExpression(node.Function, modifier)
+		exp.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&exp)
+
+	case *ArrayLiteral:
+		array := *node
+		array.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&array)
+
+	case *HashLiteral:
+		hash := *node
+		hash.Pairs = make(map[Expression]Expression, len(node.Pairs))
+		hash.Order = make([]Expression, 0, len(node.Pairs))
+		for _, entry := range node.Entries() {
+			if spread, ok := entry.(*SpreadExpression); ok {
+				if modified, ok := Modify(spread, modifier).(*SpreadExpression); ok {
+					spread = modified
+				}
+				hash.Order = append(hash.Order, spread)
+				continue
+			}
+
+			key := modifyExpression(entry, modifier)
+			hash.Pairs[key] = modifyExpression(node.Pairs[entry], modifier)
+			hash.Order = append(hash.Order, key)
+		}
+		return modifier(&hash)
+
+	case *SpreadExpression:
+		exp := *node
+		exp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&exp)
+
+	default:
+		return modifier(node)
+	}
+}
+
+func modifyStatements(statements []Statement, modifier ModifierFunc) []Statement {
+	if statements == nil {
+		return nil
+	}
+
+	modified := make([]Statement, len(statements))
+	for i, statement := range statements {
+		modified[i] = statement
+		if statement, ok := Modify(statement, modifier).(Statement); ok {
+			modified[i] = statement
+		}
+	}
+
+	return modified
+}
+
+func modifyExpressions(expressions []Expression, modifier ModifierFunc) []Expression {
+	if expressions == nil {
+		return nil
+	}
+
+	modified := make([]Expression, len(expressions))
+	for i, expression := range expressions {
+		modified[i] = modifyExpression(expression, modifier)
+	}
+
+	return modified
+}
+
+func modifyExpression(expression Expression, modifier ModifierFunc) Expression {
+	if expression == nil {
+		return nil
+	}
+
+	if modified, ok := Modify(expression, modifier).(Expression); ok {
+		return modified
+	}
+	return expression
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+
+	if modified, ok := Modify(block, modifier).(*BlockStatement); ok {
+		return modified
+	}
+	return block
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+
+	if modified, ok := Modify(ident, modifier).(*Identifier); ok {
+		return modified
+	}
+	return ident
+}
@@ -0,0 +1,136 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		if ident, ok := node.(*Identifier); ok && ident.Value == "one" {
+			return &Identifier{Value: "two"}
+		}
+
+		integer, ok := node.(*IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		return &IntegerLiteral{Value: 2}
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		},
+		{&InfixExpression{Left: one(), Operator: "+", Right: two()}, &InfixExpression{Left: two(), Operator: "+", Right: two()}},
+		{&PrefixExpression{Operator: "-", Right: one()}, &PrefixExpression{Operator: "-", Right: two()}},
+		{&IndexExpression{Left: one(), Index: one()}, &IndexExpression{Left: two(), Index: two()}},
+		{&AssignExpression{Target: &Identifier{Value: "x"}, Operator: "=", Value: one()}, &AssignExpression{Target: &Identifier{Value: "x"}, Operator: "=", Value: two()}},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{&ReturnStatement{ReturnValue: one()}, &ReturnStatement{ReturnValue: two()}},
+		{&LetStatement{Name: &Identifier{Value: "x"}, Value: one()}, &LetStatement{Name: &Identifier{Value: "x"}, Value: two()}},
+		{&LetStatement{Name: &Identifier{Value: "one"}, Value: one()}, &LetStatement{Name: &Identifier{Value: "two"}, Value: two()}},
+		{
+			&LetStatement{Pattern: &ArrayLiteral{Elements: []Expression{&Identifier{Value: "one"}, one()}}, Value: one()},
+			&LetStatement{Pattern: &ArrayLiteral{Elements: []Expression{&Identifier{Value: "two"}, two()}}, Value: two()},
+		},
+		{&ThrowStatement{Value: one()}, &ThrowStatement{Value: two()}},
+		{
+			&WhileStatement{Condition: one(), Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}}},
+			&WhileStatement{Condition: two(), Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}}},
+		},
+		{
+			&ForStatement{Key: &Identifier{Value: "one"}, Value: &Identifier{Value: "x"}, Iterable: one(), Body: &BlockStatement{Statements: []Statement{}}},
+			&ForStatement{Key: &Identifier{Value: "two"}, Value: &Identifier{Value: "x"}, Iterable: two(), Body: &BlockStatement{Statements: []Statement{}}},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Parameter{{Name: &Identifier{Value: "one"}, Default: one()}, {Pattern: &ArrayLiteral{Elements: []Expression{one()}}}},
+				Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&FunctionLiteral{
+				Parameters: []*Parameter{{Name: &Identifier{Value: "two"}, Default: two()}, {Pattern: &ArrayLiteral{Elements: []Expression{two()}}}},
+				Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{one(), &SpreadExpression{Value: one()}}}, &CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{two(), &SpreadExpression{Value: two()}}}},
+		{&ArrayLiteral{Elements: []Expression{one(), one()}}, &ArrayLiteral{Elements: []Expression{two(), two()}}},
+		{
+			&MatchExpression{Subject: one(), Arms: []*MatchArm{{Pattern: one(), Guard: one(), Body: one()}}},
+			&MatchExpression{Subject: two(), Arms: []*MatchArm{{Pattern: two(), Guard: two(), Body: two()}}},
+		},
+		{
+			&TryExpression{
+				Block:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Param:   &Identifier{Value: "one"},
+				Catch:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Finally: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&TryExpression{
+				Block:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Param:   &Identifier{Value: "two"},
+				Catch:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Finally: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		before := tt.input.String()
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+		}
+		if tt.input.String() != before {
+			t.Errorf("input was modified. got=%q, want=%q", tt.input.String(), before)
+		}
+	}
+
+	hashLiteral := &HashLiteral{
+		Pairs: map[Expression]Expression{
+			one(): one(),
+			one(): one(),
+		},
+	}
+
+	modified := Modify(hashLiteral, turnOneIntoTwo).(*HashLiteral)
+
+	if len(modified.Order) != 2 {
+		t.Fatalf("wrong number of entries. got=%d", len(modified.Order))
+	}
+	for _, key := range modified.Entries() {
+		key, _ := key.(*IntegerLiteral)
+		if key.Value != 2 {
+			t.Errorf("key is not %d, got=%d", 2, key.Value)
+		}
+		value, _ := modified.Pairs[key].(*IntegerLiteral)
+		if value.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, value.Value)
+		}
+	}
+	for key, value := range hashLiteral.Pairs {
+		if key.(*IntegerLiteral).Value != 1 || value.(*IntegerLiteral).Value != 1 {
+			t.Errorf("input was modified. got=%s", hashLiteral)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"monkey"
+	"monkey/parser"
+	"monkey/repl"
+	"os"
+	"os/user"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runFile(os.Args[1]))
+	}
+
+	user, err := user.Current()
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
+	fmt.Printf("Feel free to type in commands\n")
+	repl.Start(os.Stdin, os.Stdout)
+}
+
+// runFile evaluates a Monkey script, reporting any errors to stderr. It
+// returns the exit status for the process.
+func runFile(filename string) int {
+	_, err := monkey.New().RunFile(filename)
+	switch err := err.(type) {
+	case nil:
+		return 0
+	case *monkey.ParseError:
+		parser.RenderDiagnostics(os.Stderr, err.Source, err.Diagnostics)
+	case *monkey.RuntimeError:
+		fmt.Fprintln(os.Stderr, err.Err.Traceback())
+	default:
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	return 1
+}
@@ -0,0 +1,201 @@
+package monkey
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"monkey/evaluator"
+	"monkey/object"
+	"reflect"
+)
+
+// ToObject converts a Go value into a Monkey object. It accepts nil, bools,
+// integers, floats, strings, *big.Int, and slices, arrays and maps of those.
+// Map keys have to convert to integers, strings or bools. A slice or map that
+// contains itself cannot be converted. An object.Object is returned as it is.
+func ToObject(value interface{}) (object.Object, error) {
+	return toObject(value, make(map[visit]bool))
+}
+
+// visit identifies a slice or map by where its contents are stored
+type visit struct {
+	kind reflect.Kind
+	ptr  uintptr
+	len  int
+}
+
+// toObject converts value like ToObject. inProgress holds the slices and maps
+// being converted, which value must not be one of.
+func toObject(value interface{}, inProgress map[visit]bool) (object.Object, error) {
+	switch value := value.(type) {
+	case nil:
+		return evaluator.NULL, nil
+	case object.Object:
+		return value, nil
+	case bool:
+		if value {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+	case string:
+		return &object.String{Value: value}, nil
+	case *big.Int:
+		if value.IsInt64() {
+			return &object.Integer{Value: value.Int64()}, nil
+		}
+		return &object.BigInt{Value: new(big.Int).Set(value)}, nil
+	}
+
+	v := reflect.ValueOf(value)
+
+	if kind := v.Kind(); (kind == reflect.Slice || kind == reflect.Map) && v.Pointer() != 0 {
+		key := visit{kind: kind, ptr: v.Pointer(), len: v.Len()}
+		if inProgress[key] {
+			return nil, fmt.Errorf("cannot convert cyclic value of type %T", value)
+		}
+		inProgress[key] = true
+		defer delete(inProgress, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := v.Uint(); u > math.MaxInt64 {
+			return &object.BigInt{Value: new(big.Int).SetUint64(u)}, nil
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+
+	case reflect.Slice, reflect.Array:
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			element, err := toObject(v.Index(i).Interface(), inProgress)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, v.Len())}
+
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := toObject(iter.Key().Interface(), inProgress)
+			if err != nil {
+				return nil, err
+			}
+
+			hashKey, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+
+			val, err := toObject(iter.Value().Interface(), inProgress)
+			if err != nil {
+				return nil, err
+			}
+
+			hash.Pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: val}
+		}
+		return hash, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %T to a Monkey value", value)
+}
+
+// FromObject converts a Monkey object into a Go value. Null becomes nil,
+// booleans, integers, floats and strings become bool, int64, float64 and
+// string, and big integers *big.Int. Arrays become []interface{}, and hashes
+// map[string]interface{} if all their keys are strings, or
+// map[interface{}]interface{} otherwise. An array or hash that contains
+// itself cannot be converted. Any other object, such as a function, is
+// returned as it is.
+func FromObject(obj object.Object) (interface{}, error) {
+	return fromObject(obj, make(map[object.Object]bool))
+}
+
+// fromObject converts obj like FromObject. inProgress holds the arrays and
+// hashes being converted, which obj must not be one of.
+func fromObject(obj object.Object, inProgress map[object.Object]bool) (interface{}, error) {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.BigInt:
+		return new(big.Int).Set(obj.Value), nil
+	case *object.Float:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+
+	case *object.Array:
+		if inProgress[obj] {
+			return nil, fmt.Errorf("cannot convert an ARRAY that contains itself")
+		}
+		inProgress[obj] = true
+		defer delete(inProgress, obj)
+
+		elements := make([]interface{}, len(obj.Elements))
+		for i, element := range obj.Elements {
+			value, err := fromObject(element, inProgress)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return elements, nil
+
+	case *object.Hash:
+		if inProgress[obj] {
+			return nil, fmt.Errorf("cannot convert a HASH that contains itself")
+		}
+		inProgress[obj] = true
+		defer delete(inProgress, obj)
+
+		stringKeys := true
+		for _, pair := range obj.Pairs {
+			if _, ok := pair.Key.(*object.String); !ok {
+				stringKeys = false
+				break
+			}
+		}
+
+		if stringKeys {
+			m := make(map[string]interface{}, len(obj.Pairs))
+			for _, pair := range obj.Pairs {
+				value, err := fromObject(pair.Value, inProgress)
+				if err != nil {
+					return nil, err
+				}
+				m[pair.Key.(*object.String).Value] = value
+			}
+			return m, nil
+		}
+
+		m := make(map[interface{}]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, err := fromObject(pair.Key, inProgress)
+			if err != nil {
+				return nil, err
+			}
+			value, err := fromObject(pair.Value, inProgress)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+
+	default:
+		return obj, nil
+	}
+}
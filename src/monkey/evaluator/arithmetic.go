@@ -0,0 +1,151 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"monkey/object"
+)
+
+// maxExponent and maxShiftCount reject absurd operands of ** and << early.
+// maxIntegerBits bounds the size of every BigInt result, so that a single
+// operation cannot take unbounded time or memory; it is checked against the
+// size of the operands, before the result is computed.
+const (
+	maxExponent    = 1 << 20
+	maxShiftCount  = 1 << 24
+	maxIntegerBits = 1 << 20
+)
+
+// checkedIntegerOperators implement the integer operators that can
+// overflow. Each returns the wrapped result, and whether it overflowed.
+var checkedIntegerOperators = map[string]func(a, b int64) (int64, bool){
+	"+":  addInt64,
+	"-":  subInt64,
+	"*":  mulInt64,
+	"/":  divInt64,
+	"**": powInt64,
+	"<<": shlInt64,
+}
+
+func addInt64(a, b int64) (int64, bool) {
+	result := a + b
+	// overflow happened if both operands have a sign different from the result
+	return result, (a^result)&(b^result) < 0
+}
+
+func subInt64(a, b int64) (int64, bool) {
+	result := a - b
+	// overflow happened if the operands have different signs, and the result
+	// has a sign different from a
+	return result, (a^b)&(a^result) < 0
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, false
+	}
+
+	result := a * b
+	overflowed := result/b != a ||
+		(a == -1 && b == math.MinInt64) ||
+		(b == -1 && a == math.MinInt64)
+
+	return result, overflowed
+}
+
+// divInt64 divides a by a non-zero b
+func divInt64(a, b int64) (int64, bool) {
+	return a / b, a == math.MinInt64 && b == -1
+}
+
+// shlInt64 shifts a left by a non-negative count
+func shlInt64(a, count int64) (int64, bool) {
+	if count >= 64 {
+		return 0, a != 0
+	}
+
+	result := a << uint64(count)
+	return result, result>>uint64(count) != a
+}
+
+func negInt64(a int64) (int64, bool) {
+	return -a, a == math.MinInt64
+}
+
+// powInt64 raises base to a non-negative exponent by repeated squaring
+func powInt64(base, exponent int64) (int64, bool) {
+	result := int64(1)
+	overflowed := false
+
+	for exponent > 0 {
+		var o bool
+		if exponent&1 == 1 {
+			result, o = mulInt64(result, base)
+			overflowed = overflowed || o
+		}
+
+		exponent >>= 1
+		if exponent > 0 {
+			base, o = mulInt64(base, base)
+			overflowed = overflowed || o
+		}
+	}
+
+	return result, overflowed
+}
+
+// toBigInt converts an Integer or a BigInt to a *big.Int. The result must not
+// be modified, as it may be shared with obj.
+func toBigInt(obj object.Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value), true
+	case *object.BigInt:
+		return obj.Value, true
+	default:
+		return nil, false
+	}
+}
+
+// resultBits returns an upper bound on the bit length of the result of
+// operator applied to a and b. Only operators that can grow their operands
+// are considered; for the others it returns 0.
+func resultBits(operator string, a, b *big.Int) uint64 {
+	aBits, bBits := uint64(a.BitLen()), uint64(b.BitLen())
+
+	switch operator {
+	case "+", "-":
+		if aBits > bBits {
+			return aBits + 1
+		}
+		return bBits + 1
+	case "*":
+		return aBits + bBits
+	case "**":
+		if aBits <= 1 || !b.IsUint64() {
+			return aBits
+		}
+		// |a| is mant * 2**exp, so |a| ** b has about b * log2(|a|) bits
+		mant := new(big.Float)
+		exp := new(big.Float).SetInt(a).MantExp(mant)
+		m, _ := mant.Float64()
+		log2 := float64(exp) + math.Log2(math.Abs(m))
+		return uint64(math.Ceil(log2*float64(b.Uint64()))) + 1
+	case "<<":
+		if aBits == 0 || !b.IsUint64() {
+			return aBits
+		}
+		return aBits + b.Uint64()
+	default:
+		return 0
+	}
+}
+
+// normalizeBigInt demotes value to an Integer if it fits in one, so integers
+// only become a BigInt while they need to be
+func normalizeBigInt(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInt{Value: value}
+}
@@ -2,14 +2,18 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"monkey/object"
+	"unicode/utf8"
 )
 
-var builtins = map[string]*object.Builtin{
+// defaultBuiltins are the builtin functions of DefaultBuiltins
+var defaultBuiltins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=%d", len(args), 1)
+			if err := checkArity("`len`", len(args), 1, 1); err != nil {
+				return err
 			}
 
 			switch arg := args[0].(type) {
@@ -19,7 +23,7 @@ var builtins = map[string]*object.Builtin{
 				}
 			case *object.String:
 				return &object.Integer{
-					Value: int64(len(arg.Value)),
+					Value: int64(utf8.RuneCountInString(arg.Value)),
 				}
 			default:
 				return newError("argument to `len` not supported, got=%s", args[0].Type())
@@ -28,8 +32,8 @@ var builtins = map[string]*object.Builtin{
 	},
 	"first": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := checkArity("`first`", len(args), 1, 1); err != nil {
+				return err
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
@@ -46,8 +50,8 @@ var builtins = map[string]*object.Builtin{
 	},
 	"last": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := checkArity("`last`", len(args), 1, 1); err != nil {
+				return err
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
@@ -65,8 +69,8 @@ var builtins = map[string]*object.Builtin{
 	},
 	"rest": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := checkArity("`rest`", len(args), 1, 1); err != nil {
+				return err
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
@@ -88,8 +92,8 @@ var builtins = map[string]*object.Builtin{
 	},
 	"push": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := checkArity("`push`", len(args), 2, 2); err != nil {
+				return err
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
@@ -108,6 +112,44 @@ var builtins = map[string]*object.Builtin{
 			}
 		},
 	},
+	"slice": {
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity("`slice`", len(args), 2, 3); err != nil {
+				return err
+			}
+
+			var length int
+			switch arg := args[0].(type) {
+			case *object.Array:
+				length = len(arg.Elements)
+			case *object.String:
+				length = utf8.RuneCountInString(arg.Value)
+			default:
+				return newError("argument to `slice` must be ARRAY or STRING. got %s", args[0].Type())
+			}
+
+			bounds := []int64{0, int64(length)}
+			for i, arg := range args[1:] {
+				integer, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("bounds for `slice` must be INTEGER. got %s", arg.Type())
+				}
+				bounds[i] = integer.Value
+			}
+
+			start, end := clampSliceBounds(bounds[0], bounds[1], length)
+
+			switch arg := args[0].(type) {
+			case *object.Array:
+				newElements := make([]object.Object, end-start)
+				copy(newElements, arg.Elements[start:end])
+				return &object.Array{Elements: newElements}
+			default:
+				chars := []rune(arg.(*object.String).Value)
+				return &object.String{Value: string(chars[start:end])}
+			}
+		},
+	},
 	"puts": {
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
@@ -117,4 +159,197 @@ var builtins = map[string]*object.Builtin{
 			return NULL
 		},
 	},
+	"abs": {
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity("`abs`", len(args), 1, 1); err != nil {
+				return err
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				if arg.Value < 0 {
+					return normalizeBigInt(new(big.Int).Neg(big.NewInt(arg.Value)))
+				}
+				return arg
+			case *object.BigInt:
+				return normalizeBigInt(new(big.Int).Abs(arg.Value))
+			case *object.Float:
+				return &object.Float{Value: math.Abs(arg.Value)}
+			default:
+				return newError("argument to `abs` must be INTEGER or FLOAT. got %s", args[0].Type())
+			}
+		},
+	},
+	"floor": roundingBuiltin("floor", math.Floor),
+	"ceil":  roundingBuiltin("ceil", math.Ceil),
+	"round": roundingBuiltin("round", math.Round),
+	"sqrt": {
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity("`sqrt`", len(args), 1, 1); err != nil {
+				return err
+			}
+
+			value, ok := toFloat(args[0])
+			if !ok {
+				return newError("argument to `sqrt` must be INTEGER or FLOAT. got %s", args[0].Type())
+			}
+
+			return &object.Float{Value: math.Sqrt(value)}
+		},
+	},
+	"pow": {
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity("`pow`", len(args), 2, 2); err != nil {
+				return err
+			}
+
+			base, ok := toFloat(args[0])
+			if !ok {
+				return newError("first argument to `pow` must be INTEGER or FLOAT. got %s", args[0].Type())
+			}
+
+			exponent, ok := toFloat(args[1])
+			if !ok {
+				return newError("second argument to `pow` must be INTEGER or FLOAT. got %s", args[1].Type())
+			}
+
+			return &object.Float{Value: math.Pow(base, exponent)}
+		},
+	},
+	"min": extremumBuiltin("min", func(a, b float64) bool { return a < b }),
+	"max": extremumBuiltin("max", func(a, b float64) bool { return a > b }),
+	"int": {
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity("`int`", len(args), 1, 1); err != nil {
+				return err
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInt:
+				return arg
+			case *object.Float:
+				if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+					return newError("float %s out of range for INTEGER", arg.Inspect())
+				}
+				if arg.Value >= math.MaxInt64 || arg.Value < math.MinInt64 {
+					value, _ := big.NewFloat(arg.Value).Int(nil)
+					return normalizeBigInt(value)
+				}
+				return &object.Integer{Value: int64(arg.Value)}
+			default:
+				return newError("argument to `int` must be INTEGER or FLOAT. got %s", args[0].Type())
+			}
+		},
+	},
+	"float": {
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity("`float`", len(args), 1, 1); err != nil {
+				return err
+			}
+
+			value, ok := toFloat(args[0])
+			if !ok {
+				return newError("argument to `float` must be INTEGER or FLOAT. got %s", args[0].Type())
+			}
+
+			return &object.Float{Value: value}
+		},
+	},
+}
+
+// clampSliceBounds resolves the bounds of a slice over length elements.
+// Negative bounds count back from the end, and out of range bounds are
+// clamped, so the result is always a valid, possibly empty, range.
+func clampSliceBounds(start, end int64, length int) (int, int) {
+	clamp := func(i int64) int {
+		if i < 0 {
+			i += int64(length)
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > int64(length) {
+			return length
+		}
+		return int(i)
+	}
+
+	s, e := clamp(start), clamp(end)
+	if e < s {
+		e = s
+	}
+
+	return s, e
+}
+
+// roundingBuiltin creates a builtin that rounds floats with fn. Integers are
+// already whole, so they are returned unchanged.
+func roundingBuiltin(name string, fn func(float64) float64) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity("`"+name+"`", len(args), 1, 1); err != nil {
+				return err
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInt:
+				return arg
+			case *object.Float:
+				return &object.Float{Value: fn(arg.Value)}
+			default:
+				return newError("argument to `%s` must be INTEGER or FLOAT. got %s", name, args[0].Type())
+			}
+		},
+	}
+}
+
+// extremumBuiltin creates a builtin returning the argument for which better
+// holds against every other argument, such as min or max
+func extremumBuiltin(name string, better func(a, b float64) bool) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity("`"+name+"`", len(args), 1, -1); err != nil {
+				return err
+			}
+
+			var result object.Object
+			var resultVal float64
+			for _, arg := range args {
+				value, ok := toFloat(arg)
+				if !ok {
+					return newError("arguments to `%s` must be INTEGER or FLOAT. got %s", name, arg.Type())
+				}
+
+				if result == nil || better(value, resultVal) {
+					result = arg
+					resultVal = value
+				}
+			}
+
+			return result
+		},
+	}
+}
+
+// checkArity returns an error naming callee if got arguments is not between
+// min and max, or nil if it is. A max of -1 means any number of arguments
+// from min upwards is accepted.
+func checkArity(callee string, got, min, max int) *object.Error {
+	switch {
+	case got >= min && (got <= max || max == -1):
+		return nil
+	case min == max:
+		return newError("%s expects %s, got %d", callee, pluralize(min, "argument"), got)
+	case max == -1:
+		return newError("%s expects at least %s, got %d", callee, pluralize(min, "argument"), got)
+	default:
+		return newError("%s expects %d to %s, got %d", callee, min, pluralize(max, "argument"), got)
+	}
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
 }
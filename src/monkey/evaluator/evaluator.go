@@ -1,9 +1,15 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"math/big"
 	"monkey/ast"
 	"monkey/object"
+	"monkey/token"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -15,40 +21,275 @@ var (
 
 	// FALSE is a constrant object.Boolean used for all false values
 	FALSE = &object.Boolean{Value: false}
+
+	// BREAK is the signal sent by all break statements
+	BREAK = &object.Break{}
+
+	// CONTINUE is the signal sent by all continue statements
+	CONTINUE = &object.Continue{}
 )
 
+// Config changes how an Evaluator behaves. A limit of zero means there is no
+// limit. Exceeding a limit is a fatal error, which a try expression cannot
+// catch.
+type Config struct {
+	// CheckOverflow makes integer arithmetic that overflows an int64 an error,
+	// instead of promoting the result to a BigInt. So are integer literals too
+	// large for an int64, and builtins that return a BigInt when not passed
+	// one, such as abs(-9223372036854775808).
+	CheckOverflow bool
+
+	// MaxCallDepth is the most function calls that may be in progress at
+	// once. Without a limit, deep recursion can overflow the Go stack. Calls
+	// in tail position do not grow the Go stack, and never exceed it.
+	MaxCallDepth int
+
+	// MaxSteps is the most AST nodes a single call to Eval may evaluate,
+	// counting each time a node is evaluated. A node whose value is a BigInt
+	// counts once for each 64-bit word of it, as the work done grows with
+	// its size.
+	MaxSteps int
+
+	// MaxObjects is the most values a single call to Eval may create. Every
+	// value an expression evaluates to counts, except for the value of an
+	// identifier, null and booleans. A BigInt counts once for each 64-bit
+	// word of it.
+	MaxObjects int
+
+	// Builtins are the builtin functions code can call. If nil, the
+	// Evaluator gets its own copy of DefaultBuiltins.
+	Builtins *Builtins
+}
+
+// Evaluator holds the state of an evaluation, such as the stack of function
+// calls currently in progress. An Evaluator is not safe for concurrent use.
+type Evaluator struct {
+	config   Config
+	builtins *Builtins
+	ctx      context.Context // the context of EvalContext, if any
+	frames   []frame
+	nesting  int // how many calls to Eval are in progress
+	steps    int
+	objects  int
+}
+
+// New creates a new Evaluator with the default configuration
+func New() *Evaluator {
+	return NewWithConfig(Config{})
+}
+
+// NewWithConfig creates a new Evaluator with the given configuration
+func NewWithConfig(config Config) *Evaluator {
+	builtins := config.Builtins
+	if builtins == nil {
+		builtins = DefaultBuiltins()
+	}
+
+	return &Evaluator{config: config, builtins: builtins}
+}
+
+// Builtins returns the registry of builtin functions the Evaluator uses, to
+// which a host can add its own
+func (e *Evaluator) Builtins() *Builtins {
+	return e.builtins
+}
+
 // Eval evaluates the AST node provided to it
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+// EvalContext evaluates the AST node provided to it, stopping with a fatal
+// error once ctx is done
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	return New().EvalContext(ctx, node, env)
+}
+
+// EvalContext evaluates node like Eval, but checks ctx before every function
+// call and every iteration of a loop, and stops with a fatal error once ctx
+// is cancelled or its deadline has passed
+func (e *Evaluator) EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	outer := e.ctx
+	e.ctx = ctx
+	defer func() { e.ctx = outer }()
+
+	return e.Eval(node, env)
+}
+
+// Eval evaluates the AST node provided to it. Errors produced while
+// evaluating the node are annotated with its position and the call stack.
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	if e.nesting == 0 {
+		e.steps, e.objects = 0, 0
+	}
+
+	e.steps++
+	if e.config.MaxSteps > 0 && e.steps > e.config.MaxSteps {
+		return e.fatalError(node, "maximum of %d evaluation steps exceeded", e.config.MaxSteps)
+	}
+
+	e.nesting++
+	result := e.eval(node, env)
+	e.nesting--
+
+	if err, ok := result.(*object.Error); ok {
+		e.annotate(err, node.Pos())
+		return result
+	}
+
+	size := valueSize(node, result)
+	if size > 1 {
+		// computing a large value takes time in proportion to its size
+		e.steps += size - 1
+		if e.config.MaxSteps > 0 && e.steps > e.config.MaxSteps {
+			return e.fatalError(node, "maximum of %d evaluation steps exceeded", e.config.MaxSteps)
+		}
+	}
+
+	if e.config.MaxObjects > 0 && size > 0 {
+		e.objects += size
+		if e.objects > e.config.MaxObjects {
+			return e.fatalError(node, "maximum of %d objects exceeded", e.config.MaxObjects)
+		}
+	}
+
+	return result
+}
+
+// Call calls fn, a function or a builtin, with args. It is how a Go program
+// calls back into Monkey code; in stack traces, the call is made at <host>.
+func (e *Evaluator) Call(fn object.Object, args []object.Object) object.Object {
+	if e.nesting == 0 {
+		e.steps, e.objects = 0, 0
+	}
+
+	e.nesting++
+	defer func() { e.nesting-- }()
+
+	call := &ast.CallExpression{
+		Token: token.Token{Pos: token.Position{Filename: "<host>"}},
+	}
+
+	if err := e.pushFrame(call, fn, args); err != nil {
+		return err
+	}
+	result := e.applyFunction(fn, args)
+	e.popFrame()
+
+	return result
+}
+
+// valueSize returns how much result, the value of node, counts against
+// Config.MaxObjects: nothing if it is not a new value, the number of words
+// of a BigInt, and one for anything else
+func valueSize(node ast.Node, result object.Object) int {
+	if _, ok := node.(ast.Expression); !ok {
+		return 0
+	}
+	if _, ok := node.(*ast.Identifier); ok {
+		return 0
+	}
+
+	switch result := result.(type) {
+	case nil, *object.Null, *object.Boolean:
+		return 0
+	case *object.BigInt:
+		if words := len(result.Value.Bits()); words > 1 {
+			return words
+		}
+		return 1
+	default:
+		return 1
+	}
+}
+
+// fatalError creates an error that ends the evaluation at node, such as the
+// one for exceeding a limit of the Config
+func (e *Evaluator) fatalError(node ast.Node, format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Fatal = true
+	e.annotate(err, node.Pos())
+	return err
+}
+
+// checkContext returns a fatal error at node if the context of EvalContext
+// is done
+func (e *Evaluator) checkContext(node ast.Node) *object.Error {
+	if e.ctx == nil {
+		return nil
+	}
+
+	if err := e.ctx.Err(); err != nil {
+		return e.fatalError(node, "evaluation cancelled: %s", err)
+	}
+	return nil
+}
+
+func (e *Evaluator) eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.Eval(node.Expression, env)
 
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		val := e.Eval(node.ReturnValue, env)
+		if endsBlock(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
+	case *ast.ThrowStatement:
+		val := e.Eval(node.Value, env)
+		if endsBlock(val) {
+			return val
+		}
+		return newThrownError(val)
+
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
-		if isError(val) {
+		val := e.Eval(node.Value, env)
+		if endsBlock(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			return e.destructure(node.Pattern, val, env)
+		}
+		if fn, ok := val.(*object.Function); ok && fn.Name == "" {
+			fn.Name = node.Name.Value
+		}
 		env.Set(node.Name.Value, val)
 
+	case *ast.WhileStatement:
+		return e.evalWhileStatement(node, env)
+
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
+	case *ast.BigIntegerLiteral:
+		if e.config.CheckOverflow {
+			return newError("integer overflow: %s", node.Value)
+		}
+		return normalizeBigInt(node.Value)
+
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -58,30 +299,43 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return e.evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return e.evalLogicalExpression(node, env)
+		}
+
+		left := e.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
 
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return e.evalInfixExpression(node.Operator, left, right)
+
+	case *ast.AssignExpression:
+		return e.evalAssignExpression(node, env)
+
+	case *ast.TryExpression:
+		return e.evalTryExpression(node, env)
 
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
+
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, env)
 
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return e.evalIdentifier(node, env)
 
 	case *ast.FunctionLiteral:
 		params := node.Parameters
@@ -92,20 +346,38 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			Env:        env,
 		}
 
+	case *ast.MacroLiteral:
+		return newError("macros can only be defined by a top-level let")
+
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
+		if isQuoteCall(node) {
+			if err := checkArity("`quote`", len(node.Arguments), 1, 1); err != nil {
+				return err
+			}
+			return e.quote(node.Arguments[0], env)
 		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+
+		function, args, err := e.evalCall(node, env)
+		if err != nil {
+			return err
 		}
 
-		return applyFunction(function, args)
+		if err := e.checkContext(node); err != nil {
+			return err
+		}
+		if err := e.pushFrame(node, function, args); err != nil {
+			return err
+		}
+		result := e.applyFunction(function, args)
+		if err, ok := result.(*object.Error); ok {
+			e.annotate(err, node.Pos())
+		}
+		e.popFrame()
+
+		return result
 
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
+		elements := e.evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
@@ -115,12 +387,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := e.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
 
-		index := Eval(node.Index, env)
+		index := e.Eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
@@ -128,34 +400,39 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalIndexExpression(left, index)
 
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return e.evalHashLiteral(node, env)
+
+	case *ast.BadStatement, *ast.BadExpression:
+		return newError("syntax error at %s", node.Pos())
 	}
 
 	return nil
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		result = e.Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
 			return result.Value
 		case *object.Error:
 			return result
+		case *object.Break, *object.Continue:
+			return newError("%s outside of a loop", result.Inspect())
 		}
 	}
 
 	return result
 }
 
-func evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
+func (e *Evaluator) evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range stmts {
-		result = Eval(statement, env)
+		result = e.Eval(statement, env)
 
 		if returnValue, ok := result.(*object.ReturnValue); ok {
 			return returnValue.Value
@@ -165,12 +442,14 @@ func evalStatements(stmts []ast.Statement, env *object.Environment) object.Objec
 	return result
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func (e *Evaluator) evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return e.evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalBitwiseNotOperatorExpression(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -189,19 +468,157 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+func (e *Evaluator) evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		value, overflowed := negInt64(right.Value)
+		if overflowed {
+			if e.config.CheckOverflow {
+				return newError("integer overflow: -(%d)", right.Value)
+			}
+			return normalizeBigInt(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: value}
+	case *object.BigInt:
+		return normalizeBigInt(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
+}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+func evalBitwiseNotOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: ^right.Value}
+	case *object.BigInt:
+		return normalizeBigInt(new(big.Int).Not(right.Value))
+	default:
+		return newError("unknown operator: ~%s", right.Type())
+	}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+// evalLogicalExpression evaluates && and ||. The right side is only evaluated
+// if the left side does not already decide the result, and the result is the
+// deciding operand itself, so `name || "default"` works as expected.
+func (e *Evaluator) evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	if node.Operator == "&&" && !isTruthy(left) {
+		return left
+	}
+
+	if node.Operator == "||" && isTruthy(left) {
+		return left
+	}
+
+	return e.Eval(node.Right, env)
+}
+
+// evalAssignExpression updates the variable, array element or hash entry that
+// node assigns to, and returns the assigned value
+func (e *Evaluator) evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		current, ok := env.Get(target.Value)
+		if !ok {
+			return newError("assignment to undeclared identifier: %s", target.Value)
+		}
+
+		value := e.assignedValue(node, current, env)
+		if endsBlock(value) {
+			return value
+		}
+		if fn, ok := value.(*object.Function); ok && fn.Name == "" {
+			fn.Name = target.Value
+		}
+
+		env.Assign(target.Value, value)
+		return value
+
+	case *ast.IndexExpression:
+		left := e.Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+
+		index := e.Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		var current object.Object
+		if node.Operator != "=" {
+			current = evalIndexExpression(left, index)
+			if isError(current) {
+				return current
+			}
+		}
+
+		value := e.assignedValue(node, current, env)
+		if endsBlock(value) {
+			return value
+		}
+
+		return evalIndexAssignment(left, index, value)
+
+	default:
+		return newError("cannot assign to %s", node.Target)
+	}
+}
+
+// assignedValue evaluates the value node assigns. For a compound assignment
+// such as +=, it is combined with the current value of the target.
+func (e *Evaluator) assignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	value := e.Eval(node.Value, env)
+	if endsBlock(value) || node.Operator == "=" {
+		return value
+	}
+
+	operator := strings.TrimSuffix(node.Operator, "=")
+	return e.evalInfixExpression(operator, current, value)
+}
+
+func evalIndexAssignment(left, index, value object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("index assignment not supported: %s[%s]", left.Type(), index.Type())
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newError("index out of range: %d, length is %d", idx.Value, len(left.Elements))
+		}
+
+		left.Elements[idx.Value] = value
+		return value
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
+		return value
+
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+}
+
+func (e *Evaluator) evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+		return e.evalIntegerInfixExpression(operator, left, right)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
@@ -215,23 +632,161 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+func (e *Evaluator) evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	if (operator == "<<" || operator == ">>") && rightVal < 0 {
+		return newError("negative shift count: %d", rightVal)
+	}
+
+	switch operator {
+	case "+", "-", "*", "/", "**", "<<":
+		if operator == "/" && rightVal == 0 {
+			return newError("division by zero")
+		}
+		if operator == "**" && rightVal < 0 {
+			return &object.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+		}
+
+		result, overflowed := checkedIntegerOperators[operator](leftVal, rightVal)
+		if overflowed {
+			if e.config.CheckOverflow {
+				return newError("integer overflow: %d %s %d", leftVal, operator, rightVal)
+			}
+			return evalBigIntegerInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: result}
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case ">>":
+		return &object.Integer{Value: leftVal >> uint64(rightVal)}
+	case "..":
+		return &object.Range{Start: leftVal, End: rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalBigIntegerInfixExpression evaluates an infix expression between
+// integers where at least one side is a BigInt, or where the int64 result
+// would have overflowed. The result is demoted back to an Integer if it fits.
+func evalBigIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal, _ := toBigInt(left)
+	rightVal, _ := toBigInt(right)
+
+	if operator == "**" && rightVal.Sign() >= 0 && (!rightVal.IsUint64() || rightVal.Uint64() > maxExponent) {
+		return newError("exponent too large: %s", rightVal)
+	}
+	if bits := resultBits(operator, leftVal, rightVal); bits > maxIntegerBits {
+		return newError("integer too large: the result of %s would have up to %d bits, the most is %d",
+			operator, bits, maxIntegerBits)
+	}
+
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return normalizeBigInt(new(big.Int).Add(leftVal, rightVal))
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return normalizeBigInt(new(big.Int).Sub(leftVal, rightVal))
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return normalizeBigInt(new(big.Int).Mul(leftVal, rightVal))
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return normalizeBigInt(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError("modulo by zero")
+		}
+		return normalizeBigInt(new(big.Int).Rem(leftVal, rightVal))
+	case "**":
+		if rightVal.Sign() < 0 {
+			return evalFloatInfixExpression(operator, left, right)
+		}
+		return normalizeBigInt(new(big.Int).Exp(leftVal, rightVal, nil))
+	case "&":
+		return normalizeBigInt(new(big.Int).And(leftVal, rightVal))
+	case "|":
+		return normalizeBigInt(new(big.Int).Or(leftVal, rightVal))
+	case "^":
+		return normalizeBigInt(new(big.Int).Xor(leftVal, rightVal))
+	case "<<", ">>":
+		if rightVal.Sign() < 0 {
+			return newError("negative shift count: %s", rightVal)
+		}
+		if !rightVal.IsUint64() || rightVal.Uint64() > maxShiftCount {
+			return newError("shift count too large: %s", rightVal)
+		}
+		if operator == "<<" {
+			return normalizeBigInt(new(big.Int).Lsh(leftVal, uint(rightVal.Uint64())))
+		}
+		return normalizeBigInt(new(big.Int).Rsh(leftVal, uint(rightVal.Uint64())))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalFloatInfixExpression evaluates an infix expression where at least one
+// side is a float, promoting the other side to a float if necessary
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal, _ := toFloat(left)
+	rightVal, _ := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -254,63 +809,394 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	}
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(ie.Condition, env)
 
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return e.Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+// evalTryExpression evaluates the try block of node, and the catch block if
+// that fails. The finally block runs after both, and only replaces their
+// result if it returns, breaks, continues or fails itself. A fatal error is
+// neither caught nor followed by the finally block.
+func (e *Evaluator) evalTryExpression(node *ast.TryExpression, env *object.Environment) object.Object {
+	result := e.Eval(node.Block, env)
+
+	if err, ok := result.(*object.Error); ok && !err.Fatal && node.Catch != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		catchEnv.Set(node.Param.Value, caughtError(err))
+		result = e.Eval(node.Catch, catchEnv)
+	}
+
+	if err, ok := result.(*object.Error); ok && err.Fatal {
+		return err
+	}
+
+	if node.Finally != nil {
+		final := e.Eval(node.Finally, env)
+		switch final.(type) {
+		case *object.Error, *object.ReturnValue, *object.Break, *object.Continue:
+			return final
+		}
+	}
+
+	return result
+}
+
+// caughtError converts err into the hash a catch block sees, with the
+// message, the thrown value (null for errors the interpreter raised) and the
+// call stack at the point of failure, innermost call first
+func caughtError(err *object.Error) *object.Hash {
+	value := err.Value
+	if value == nil {
+		value = NULL
+	}
+
+	stack := make([]object.Object, len(err.Stack))
+	for i, frame := range err.Stack {
+		stack[i] = &object.String{Value: frame.String()}
+	}
+
+	hash := &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+	for _, pair := range []object.HashPair{
+		{Key: &object.String{Value: "message"}, Value: &object.String{Value: err.Message}},
+		{Key: &object.String{Value: "value"}, Value: value},
+		{Key: &object.String{Value: "stack"}, Value: &object.Array{Elements: stack}},
+	} {
+		hash.Pairs[pair.Key.(object.Hashable).HashKey()] = pair
+	}
+
+	return hash
+}
+
+func (e *Evaluator) evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	arm, armEnv, err := e.selectMatchArm(node, env)
+	if err != nil {
+		return err
+	}
+
+	return e.Eval(arm.Body, armEnv)
+}
+
+// selectMatchArm returns the first arm of node whose pattern and guard match
+// the subject, along with the environment to evaluate its body in. Each arm
+// gets its own environment for the identifiers its pattern binds.
+func (e *Evaluator) selectMatchArm(node *ast.MatchExpression, env *object.Environment) (*ast.MatchArm, *object.Environment, object.Object) {
+	subject := e.Eval(node.Subject, env)
+	if isError(subject) {
+		return nil, nil, subject
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+
+		mismatch, err := e.bindPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return nil, nil, err
+		}
+		if mismatch != "" {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := e.Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return nil, nil, guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return arm, armEnv, nil
+	}
+
+	return nil, nil, newError("no match arm matches %s", subject.Inspect())
+}
+
+// destructure binds the identifiers in pattern to the parts of value, and
+// fails if value does not have the shape of the pattern
+func (e *Evaluator) destructure(pattern ast.Expression, value object.Object, env *object.Environment) object.Object {
+	mismatch, err := e.bindPattern(pattern, value, env)
+	if err != nil {
+		return err
+	}
+	if mismatch != "" {
+		return newError("cannot destructure %s into %s: %s", value.Type(), pattern, mismatch)
+	}
+	return nil
+}
+
+// bindPattern matches value against pattern, binding the identifiers in the
+// pattern in env along the way. It returns a description of the first part of
+// value that does not match, or "" if all of it does. The second result is an
+// error, if evaluating part of the pattern failed.
+func (e *Evaluator) bindPattern(pattern ast.Expression, value object.Object, env *object.Environment) (string, object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			env.Set(pattern.Value, value)
+		}
+		return "", nil
+
+	case *ast.ArrayLiteral:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return fmt.Sprintf("expected ARRAY, got %s", value.Type()), nil
+		}
+
+		elements := pattern.Elements
+		var rest ast.Expression
+		if n := len(elements); n > 0 {
+			if spread, ok := elements[n-1].(*ast.SpreadExpression); ok {
+				rest = spread.Value
+				elements = elements[:n-1]
+			}
+		}
+
+		switch {
+		case rest == nil && len(array.Elements) != len(elements):
+			return fmt.Sprintf("expected %s, got %d", pluralize(len(elements), "element"), len(array.Elements)), nil
+		case len(array.Elements) < len(elements):
+			return fmt.Sprintf("expected at least %s, got %d", pluralize(len(elements), "element"), len(array.Elements)), nil
+		}
+
+		for i, element := range elements {
+			if mismatch, err := e.bindPattern(element, array.Elements[i], env); mismatch != "" || err != nil {
+				return mismatch, err
+			}
+		}
+
+		if rest != nil {
+			remaining := make([]object.Object, len(array.Elements)-len(elements))
+			copy(remaining, array.Elements[len(elements):])
+			return e.bindPattern(rest, &object.Array{Elements: remaining}, env)
+		}
+
+		return "", nil
+
+	case *ast.HashLiteral:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return fmt.Sprintf("expected HASH, got %s", value.Type()), nil
+		}
+
+		for _, keyNode := range pattern.Entries() {
+			key := e.Eval(keyNode, env)
+			if isError(key) {
+				return "", key
+			}
+
+			hashKey, ok := key.(object.Hashable)
+			if !ok {
+				return "", newError("unusable as hash key: %s", key.Type())
+			}
+
+			pair, ok := hash.Pairs[hashKey.HashKey()]
+			if !ok {
+				if _, ok := key.(*object.String); ok {
+					return fmt.Sprintf("missing key %s", strconv.Quote(key.Inspect())), nil
+				}
+				return fmt.Sprintf("missing key %s", key.Inspect()), nil
+			}
+
+			if mismatch, err := e.bindPattern(pattern.Pairs[keyNode], pair.Value, env); mismatch != "" || err != nil {
+				return mismatch, err
+			}
+		}
+
+		return "", nil
+
+	default:
+		literal := e.Eval(pattern, env)
+		if isError(literal) {
+			return "", literal
+		}
+		if !objectsEqual(literal, value) {
+			return fmt.Sprintf("expected %s, got %s", literal.Inspect(), value.Inspect()), nil
+		}
+		return "", nil
+	}
+}
+
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = e.Eval(statement, env)
+
+		if endsBlock(result) {
+			return result
+		}
+	}
+
+	return result
+}
+
+// endsBlock reports whether the result of a statement skips the rest of the
+// block it is in
+func endsBlock(result object.Object) bool {
+	if result == nil {
+		return false
+	}
+
+	rt := result.Type()
+	return rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+		rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ
+}
+
+func (e *Evaluator) evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		if err := e.checkContext(node); err != nil {
+			return err
+		}
+
+		condition := e.Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		if stop, result := loopControl(e.Eval(node.Body, env)); stop {
+			return result
+		}
+	}
+}
+
+// evalForStatement runs the body of node for each item of its iterable. The
+// loop variables are bound in a new environment for every iteration, so
+// closures created in the body each see their own values.
+func (e *Evaluator) evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := e.Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
 
-		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+	iterate := func(key, value object.Object) (bool, object.Object) {
+		if err := e.checkContext(node); err != nil {
+			return true, err
+		}
+
+		loopEnv := object.NewEnclosedEnvironment(env)
+		if node.Key != nil {
+			loopEnv.Set(node.Key.Value, key)
+		}
+		loopEnv.Set(node.Value.Value, value)
+
+		return loopControl(e.Eval(node.Body, loopEnv))
+	}
+
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		// the body may change the array, so its length is checked every time
+		for i := 0; i < len(iterable.Elements); i++ {
+			if stop, result := iterate(&object.Integer{Value: int64(i)}, iterable.Elements[i]); stop {
+				return result
+			}
+		}
+
+	case *object.String:
+		i := int64(0)
+		for _, ch := range iterable.Value {
+			if stop, result := iterate(&object.Integer{Value: i}, &object.String{Value: string(ch)}); stop {
+				return result
+			}
+			i++
+		}
+
+	case *object.Hash:
+		for _, pair := range iterable.SortedPairs() {
+			value := pair.Value
+			if node.Key == nil {
+				value = pair.Key
+			}
+
+			if stop, result := iterate(pair.Key, value); stop {
+				return result
+			}
+		}
+
+	case *object.Range:
+		for i := iterable.Start; i < iterable.End; i++ {
+			if stop, result := iterate(&object.Integer{Value: i - iterable.Start}, &object.Integer{Value: i}); stop {
 				return result
 			}
 		}
+
+	default:
+		return newError("cannot iterate over %s", iterable.Type())
 	}
 
-	return result
+	return nil
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+// loopControl interprets the result of running the body of a loop once. It
+// reports whether the loop has to stop, and if so what it evaluates to.
+func loopControl(result object.Object) (bool, object.Object) {
+	switch result.(type) {
+	case *object.Break:
+		return true, nil
+	case *object.ReturnValue, *object.Error:
+		return true, result
+	default:
+		return false, nil
+	}
+}
+
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := e.builtins.Lookup(node.Value); ok {
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	return newError("identifier not found: %s", node.Value)
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		spread, isSpread := exp.(*ast.SpreadExpression)
+		if isSpread {
+			exp = spread.Value
+		}
+
+		evaluated := e.Eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{
 				evaluated,
 			}
 		}
 
-		result = append(result, evaluated)
+		if !isSpread {
+			result = append(result, evaluated)
+			continue
+		}
+
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			err := newError("cannot spread %s, expected ARRAY", evaluated.Type())
+			e.annotate(err, spread.Pos())
+			return []object.Object{err}
+		}
+
+		result = append(result, array.Elements...)
 	}
 
 	return result
@@ -320,6 +1206,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -341,11 +1229,44 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+// evalStringIndexExpression returns the character at index as a string.
+// Strings are indexed by character rather than by byte.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	chars := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+	max := int64(len(chars) - 1)
+
+	if idx < 0 || idx > max {
+		return NULL
+	}
+
+	return &object.String{Value: string(chars[idx])}
+}
+
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, keyNode := range node.Entries() {
+		if spread, ok := keyNode.(*ast.SpreadExpression); ok {
+			value := e.Eval(spread.Value, env)
+			if isError(value) {
+				return value
+			}
+
+			hash, ok := value.(*object.Hash)
+			if !ok {
+				err := newError("cannot spread %s into a hash, expected HASH", value.Type())
+				e.annotate(err, spread.Pos())
+				return err
+			}
+
+			for hashed, pair := range hash.Pairs {
+				pairs[hashed] = pair
+			}
+			continue
+		}
+
+		key := e.Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
@@ -355,7 +1276,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := e.Eval(node.Pairs[keyNode], env)
 		if isError(value) {
 			return value
 		}
@@ -395,6 +1316,52 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
+// objectsEqual reports whether a and b are the same value. Numbers and
+// strings are compared by value, everything else by identity.
+func objectsEqual(a, b object.Object) bool {
+	switch {
+	case isInteger(a) && isInteger(b):
+		x, _ := toBigInt(a)
+		y, _ := toBigInt(b)
+		return x.Cmp(y) == 0
+	case isNumber(a) && isNumber(b):
+		x, _ := toFloat(a)
+		y, _ := toFloat(b)
+		return x == y
+	case a.Type() == object.STRING_OBJ && b.Type() == object.STRING_OBJ:
+		return a.(*object.String).Value == b.(*object.String).Value
+	default:
+		return a == b
+	}
+}
+
+// isInteger reports whether obj is an Integer or a BigInt
+func isInteger(obj object.Object) bool {
+	_, ok := toBigInt(obj)
+	return ok
+}
+
+// isNumber reports whether obj is an integer or a float
+func isNumber(obj object.Object) bool {
+	_, ok := toFloat(obj)
+	return ok
+}
+
+// toFloat converts an integer or a float to a float64
+func toFloat(obj object.Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value), true
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value, true
+	case *object.Float:
+		return obj.Value, true
+	default:
+		return 0, false
+	}
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -414,6 +1381,24 @@ func newError(format string, a ...interface{}) *object.Error {
 	}
 }
 
+// newThrownError creates the error raised by throwing value. The message is
+// value itself if it is a string, or the "message" of a hash, so throwing a
+// caught error again keeps its message.
+func newThrownError(value object.Object) *object.Error {
+	err := &object.Error{Message: value.Inspect(), Value: value}
+
+	if hash, ok := value.(*object.Hash); ok {
+		key := (&object.String{Value: "message"}).HashKey()
+		if pair, ok := hash.Pairs[key]; ok {
+			if message, ok := pair.Value.(*object.String); ok {
+				err.Message = message.Value
+			}
+		}
+	}
+
+	return err
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -422,34 +1407,361 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// evalCall evaluates the function and the arguments of call. The third result
+// is an error, if evaluating either failed.
+func (e *Evaluator) evalCall(call *ast.CallExpression, env *object.Environment) (object.Object, []object.Object, object.Object) {
+	function := e.Eval(call.Function, env)
+	if isError(function) {
+		return nil, nil, function
+	}
+
+	args := e.evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return nil, nil, args[0]
+	}
+
+	return function, args, nil
+}
+
+// pushFrame records a call to fn on the call stack, unless that would make
+// the stack deeper than Config.MaxCallDepth allows
+func (e *Evaluator) pushFrame(call *ast.CallExpression, fn object.Object, args []object.Object) *object.Error {
+	if e.config.MaxCallDepth > 0 && len(e.frames) >= e.config.MaxCallDepth {
+		return e.fatalError(call, "maximum call depth of %d exceeded", e.config.MaxCallDepth)
+	}
+
+	e.frames = append(e.frames, newFrame(call, fn, args))
+	return nil
+}
+
+// frame is a call in progress. Its arguments are kept as they are, and only
+// summarized when an error needs a stack trace.
+type frame struct {
+	call   *ast.CallExpression
+	fn     object.Object
+	args   []object.Object
+	elided int // how many tail calls made before this one were left out
+}
+
+func newFrame(call *ast.CallExpression, fn object.Object, args []object.Object) frame {
+	return frame{call: call, fn: fn, args: args}
+}
+
+// trace describes the call for the stack trace of an error
+func (f frame) trace() object.Frame {
+	name := "<anonymous>"
+	if ident, ok := f.call.Function.(*ast.Identifier); ok {
+		name = ident.Value
+	}
+	switch fn := f.fn.(type) {
+	case *object.Function:
+		if fn.Name != "" {
+			name = fn.Name
+		}
+	case *object.Builtin:
+		if fn.Name != "" {
+			name = fn.Name
+		}
+	}
+
+	return object.Frame{
+		Function: name,
+		Pos:      f.call.Pos(),
+		Args:     summarizeArgs(f.args),
+		Elided:   f.elided,
+	}
+}
+
+func (e *Evaluator) popFrame() {
+	e.frames = e.frames[:len(e.frames)-1]
+}
+
+// pushTailFrame records a call made in tail position by the call whose frame
+// is just below depth, or by one of its tail calls. Only the most recent
+// maxTailFrames of these are kept, and fewer once Config.MaxCallDepth is
+// reached, so that tail calls take constant memory. The oldest frame kept
+// counts the ones left out.
+func (e *Evaluator) pushTailFrame(depth int, f frame) {
+	const maxTailFrames = 8
+
+	tail := e.frames[depth:]
+	if len(tail) < maxTailFrames &&
+		(e.config.MaxCallDepth == 0 || len(e.frames) < e.config.MaxCallDepth) {
+		e.frames = append(e.frames, f)
+		return
+	}
+
+	if len(tail) == 0 {
+		f.elided = e.frames[depth-1].elided + 1
+		e.frames[depth-1] = f
+		return
+	}
+
+	dropped := tail[0]
+	copy(tail, tail[1:])
+	tail[len(tail)-1] = f
+	tail[0].elided += dropped.elided + 1
+}
+
+// stackEnds is how many of the innermost and of the outermost calls the stack
+// of an error keeps, when there are more calls than twice that in progress
+const stackEnds = 10
+
+// annotate attaches a position and a snapshot of the call stack to an error,
+// unless an inner node has already done so
+func (e *Evaluator) annotate(err *object.Error, pos token.Position) {
+	if err.Pos.IsValid() {
+		return
+	}
+
+	err.Pos = pos
+
+	// innermost call first, leaving out the middle of a deep stack
+	n := len(e.frames)
+	if n <= 2*stackEnds {
+		err.Stack = make([]object.Frame, n)
+		for i, frame := range e.frames {
+			err.Stack[n-1-i] = frame.trace()
+		}
+		return
+	}
+
+	err.Stack = make([]object.Frame, 0, 2*stackEnds)
+	for i := n - 1; i >= n-stackEnds; i-- {
+		err.Stack = append(err.Stack, e.frames[i].trace())
+	}
+	err.Stack[stackEnds-1].Omitted = n - 2*stackEnds
+	for i := stackEnds - 1; i >= 0; i-- {
+		err.Stack = append(err.Stack, e.frames[i].trace())
+	}
+}
+
+// summarizeArgs formats call arguments for a stack trace, shortening long ones
+func summarizeArgs(args []object.Object) string {
+	const maxLen = 20
+
+	summary := []string{}
+	for _, arg := range args {
+		var out strings.Builder
+		writeSummary(&out, arg, maxLen, 0)
+
+		s := out.String()
+		if chars := []rune(s); len(chars) > maxLen {
+			s = string(chars[:maxLen-3]) + "..."
+		}
+		summary = append(summary, s)
+	}
+
+	return strings.Join(summary, ", ")
+}
+
+// writeSummary writes obj to out as summarizeArgs shows it. Arrays and hashes
+// are written only until out is longer than limit, and only maxSummaryDepth
+// levels deep, so that summarizing a large or cyclic value is cheap.
+func writeSummary(out *strings.Builder, obj object.Object, limit, depth int) {
+	const maxSummaryDepth = 3
+
+	switch obj := obj.(type) {
+	case *object.Function:
+		if obj.Name == "" {
+			out.WriteString("<fn>")
+		} else {
+			out.WriteString("<fn " + obj.Name + ">")
+		}
+
+	case *object.String:
+		value := obj.Value
+		for i := range value {
+			if i > limit {
+				value = value[:i]
+				break
+			}
+		}
+		if depth == 0 {
+			out.WriteString(strconv.Quote(value))
+		} else {
+			out.WriteString(value)
+		}
+
+	case *object.Array:
+		if depth == maxSummaryDepth {
+			out.WriteString("[...]")
+			return
+		}
+
+		out.WriteString("[")
+		for i, element := range obj.Elements {
+			if out.Len() > limit {
+				out.WriteString("...")
+				break
+			}
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			writeSummary(out, element, limit, depth+1)
+		}
+		out.WriteString("]")
+
+	case *object.Hash:
+		if depth == maxSummaryDepth {
+			out.WriteString("{...}")
+			return
+		}
+
+		out.WriteString("{")
+		i := 0
+		for _, pair := range obj.Pairs {
+			if out.Len() > limit {
+				out.WriteString("...")
+				break
+			}
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			writeSummary(out, pair.Key, limit, depth+1)
+			out.WriteString(": ")
+			writeSummary(out, pair.Value, limit, depth+1)
+			i++
+		}
+		out.WriteString("}")
+
+	default:
+		out.WriteString(strings.Join(strings.Fields(obj.Inspect()), " "))
+	}
+}
+
+// applyFunction calls fn with args. A call fn makes in tail position is not
+// made by a nested Eval, but returned here and made in a loop, so that tail
+// calls do not grow the Go stack. Their frames are recorded by pushTailFrame,
+// so recursion used as a loop takes constant memory as well.
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
+	depth := len(e.frames)
+	var call *ast.CallExpression // the tail call being made, if any
+
+	for {
+		result := e.callFunction(fn, args)
+		if err, ok := result.(*object.Error); ok && call != nil {
+			e.annotate(err, call.Pos())
+		}
+
+		next, ok := result.(*tailCall)
+		if !ok {
+			e.frames = e.frames[:depth]
+			return result
+		}
+
+		if err := e.checkContext(next.call); err != nil {
+			e.frames = e.frames[:depth]
+			return err
+		}
+
+		e.pushTailFrame(depth, newFrame(next.call, next.fn, next.args))
+
+		call, fn, args = next.call, next.fn, next.args
+	}
+}
+
+// callFunction calls fn with args, and returns a tailCall for the call in tail
+// position of fn, if it gets to one
+func (e *Evaluator) callFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
+		min, max := functionArity(fn)
+		if err := checkArity("function", len(args), min, max); err != nil {
+			return err
+		}
+
+		extendedEnv, err := e.extendFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
+
+		evaluated := e.evalTail(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		result := fn.Fn(args...)
+		if _, ok := result.(*object.BigInt); ok && e.config.CheckOverflow && !anyBigInt(args) {
+			return newError("integer overflow: %s(%s)", fn.Name, summarizeArgs(args))
+		}
+		return result
 
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+func anyBigInt(objects []object.Object) bool {
+	for _, obj := range objects {
+		if _, ok := obj.(*object.BigInt); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// functionArity returns the least and the most arguments fn can be called
+// with. The most is -1 if fn has a rest parameter.
+func functionArity(fn *object.Function) (int, int) {
+	min, max := 0, 0
+
+	for _, param := range fn.Parameters {
+		switch {
+		case param.Rest:
+			return min, -1
+		case param.Default == nil:
+			min++
+		}
+		max++
+	}
+
+	return min, max
+}
+
+// extendFunctionEnv binds the parameters of fn to args. Missing arguments
+// take their default value, which is evaluated now, with the parameters
+// before it already bound, so a default can refer to them.
+func (e *Evaluator) extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, object.Object) {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		var value object.Object
+
+		switch {
+		case param.Rest:
+			rest := []object.Object{}
+			if paramIdx < len(args) {
+				rest = append(rest, args[paramIdx:]...)
+			}
+			value = &object.Array{Elements: rest}
+		case paramIdx < len(args):
+			value = args[paramIdx]
+		default:
+			value = e.Eval(param.Default, env)
+			if isError(value) {
+				return nil, value
+			}
+		}
+
+		if param.Pattern != nil {
+			if err := e.destructure(param.Pattern, value, env); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		env.Set(param.Name.Value, value)
 	}
 
-	return env
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
-	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue.Value
+	switch obj := obj.(type) {
+	case *object.ReturnValue:
+		return obj.Value
+	case *object.Break, *object.Continue:
+		return newError("%s outside of a loop", obj.Inspect())
 	}
 
 	return obj
@@ -1,10 +1,13 @@
 package evaluator
 
 import (
+	"context"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -27,6 +30,18 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"5 ** 0", 1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
 	}
 
 	for _, tt := range tests {
@@ -35,6 +50,31 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 / 4.0", 2.5},
+		{"2 * .25", 0.5},
+		{"1e3 - 1", 999},
+		{"-(1.5 * 2)", -3},
+		{"7.5 % 2", 1.5},
+		{"2 ** -1", 0.5},
+		{"4 ** 0.5", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -59,6 +99,20 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1.0 == 1", true},
+		{"0.1 + 0.2 != 0.3", true},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 1", true},
+		{"1 >= 2", false},
+		{"1.5 >= 1", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
 	}
 
 	for _, tt := range tests {
@@ -86,6 +140,31 @@ func TestBangOperator(t *testing.T) {
 	}
 }
 
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"null_value() || 5", 5},
+		{"1 && 2", 2},
+		{"0 || 5", 0},
+		{`let calls = fn() { undefined }; false && calls()`, false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval("let null_value = fn() { if (false) { 1 } }; " + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
 func TestIfElseExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -98,6 +177,10 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+		{"if (false) { 1 } else if (false) { 2 } else if (true) { 3 } else { 4 }", 3},
 	}
 
 	for _, tt := range tests {
@@ -111,6 +194,105 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"match (2) { 1 => 10, 2 => 20, _ => 30 }", 20},
+		{"match (5) { 1 => 10, 2 => 20, _ => 30 }", 30},
+		{"match (-1) { -1 => 10, _ => 20 }", 10},
+		{"match (2.0) { 2 => 10, _ => 20 }", 10},
+		{`match ("b") { "a" => 1, "b" => 2 }`, 2},
+		{"match (true) { false => 1, true => 2 }", 2},
+		{"match (7) { n => n * 2 }", 14},
+		{"match (7) { n if n > 10 => 1, n if n > 5 => 2, _ => 3 }", 2},
+		{"match ([1, 2]) { [] => 0, [a] => a, [a, b] => a + b }", 3},
+		{"match ([1, 2, 3, 4]) { [a, ...rest] => a + len(rest) }", 4},
+		{"match ([1]) { [a, b, ...rest] => 1, [a, ...rest] => len(rest) }", 0},
+		{"match ([1, [2, 3]]) { [1, [x, 3]] => x, _ => 0 }", 2},
+		{`match ({"kind": "add", "a": 1, "b": 2}) { {"kind": "sub"} => 0, {"kind": "add", "a": a, "b": b} => a + b }`, 3},
+		{`match ({"a": 1}) { {"b": b} => b, _ => 9 }`, 9},
+		{"match (1) { [a] => a, {} => 2, _ => 3 }", 3},
+		{"match (3) { x => { let y = x * 2; y + 1 } }", 7},
+		{"let f = fn(x) { match (x) { 0 => { return 100 }, _ => 1 }; 2 }; f(0)", 100},
+		{"let a = 1; match (5) { a => a }; a", 1},
+		{"match (5) { 1 => 10, 2 => 20 }", "no match arm matches 5"},
+		{"match (x) { _ => 1 }", "identifier not found: x"},
+		{"match (1) { n if n + true => 1 }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"try { 1 } catch (e) { 2 }", 1},
+		{"try { 1 / 0 } catch (e) { 2 }", 2},
+		{`try { throw "oops"; 1 } catch (e) { e["message"] }`, "oops"},
+		{`try { throw [1, 2] } catch (e) { len(e["value"]) }`, 2},
+		{`try { throw {"message": "bad", "code": 3} } catch (e) { e["message"] }`, "bad"},
+		{`try { throw {"message": "bad", "code": 3} } catch (e) { e["value"]["code"] }`, 3},
+		{`try { 1 + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { 1 + true } catch (e) { if (e["value"]) { 1 } else { 2 } }`, 2},
+		{`let f = fn() { throw "x" }; let g = fn() { f() }; try { g() } catch (e) { len(e["stack"]) }`, 2},
+		{`let f = fn() { throw "x" }; try { f() } catch (e) { e["stack"][0] }`, "f() called at 1:35"},
+		{"let a = 0; try { a = 1 } finally { a = a + 10 }; a", 11},
+		{"let a = 0; try { try { 1 / 0 } finally { a = 5 } } catch (e) { a * 2 }", 10},
+		{"let f = fn() { try { return 1 } finally { 2 } }; f()", 1},
+		{"let f = fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"let f = fn() { try { throw 1 } catch (e) { return e[\"value\"] + 1 }; 0 }; f()", 2},
+		{"let n = 0; for (i in 0..5) { try { if (i == 2) { break } } finally { n += 1 } }; n", 3},
+		{`try { throw "a" } catch (e) { throw e }`, "a"},
+		{`try { try { throw "a" } catch (e) { throw e["message"] + "b" } } catch (e) { e["message"] }`, "ab"},
+		{`try { 1 } finally { throw "late" }`, "late"},
+		{`throw "uncaught"; 1`, "uncaught"},
+		{"let f = fn() { throw 42 }; f()", "42"},
+		{"try { 1 / 0 } catch (e) { 1 }; e", "identifier not found: e"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, obj.Message)
+				}
+			default:
+				t.Errorf("%q: object is not String or Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -191,6 +373,34 @@ if (10 > 1) {
 			`{"name": "Monkey"}[fn(x) {x}];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INTEGER",
+		},
+		{
+			"~true",
+			"unknown operator: ~BOOLEAN",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"true <= false",
+			"unknown operator: BOOLEAN <= BOOLEAN",
+		},
+		{
+			"true && undefined",
+			"identifier not found: undefined",
+		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"let x = 0; 10 % x",
+			"modulo by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -208,6 +418,387 @@ if (10 > 1) {
 	}
 }
 
+func TestIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+		{"2 ** 63", "integer overflow: 2 ** 63"},
+		{"-(-9223372036854775807 - 1)", "integer overflow: -(-9223372036854775808)"},
+		{"-9223372036854775807 - 1", -9223372036854775808},
+		{"-4611686018427387904 * 2", -9223372036854775808},
+		{"(-2) ** 63", -9223372036854775808},
+		{"3037000499 * 3037000499", 9223372030926249001},
+		{"9223372036854775808", "integer overflow: 9223372036854775808"},
+		{"abs(-9223372036854775807 - 1)", "integer overflow: abs(-9223372036854775808)"},
+		{"int(1.0e19)", "integer overflow: int(1e+19)"},
+		{"abs(-9223372036854775807)", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		evaluated := NewWithConfig(Config{CheckOverflow: true}).Eval(program, object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		config   Config
+		expected interface{}
+	}{
+		{"let f = fn(n) { 1 + f(n) }; f(1)", Config{MaxCallDepth: 100}, "maximum call depth of 100 exceeded"},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(99)", Config{MaxCallDepth: 100}, 99},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(100)", Config{MaxCallDepth: 100}, "maximum call depth of 100 exceeded"},
+		{"let f = fn(n) { f(n + 1) }; f(0)", Config{MaxCallDepth: 10, MaxSteps: 1000}, "maximum of 1000 evaluation steps exceeded"},
+		{"let f = fn() { g() }; let g = fn() { f() }; f()", Config{MaxCallDepth: 50, MaxSteps: 10000}, "maximum of 10000 evaluation steps exceeded"},
+		{`
+		let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+		let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+		if (even(100000)) { 1 } else { 0 }`, Config{MaxCallDepth: 1000}, 1},
+		{`
+		let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+		let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+		let f = fn(n) { if (n == 0) { 0 } else { even(2); 1 + f(n - 1) } };
+		f(10)`, Config{MaxCallDepth: 12}, 10},
+		{"let f = fn() { f() }; f()", Config{MaxSteps: 10000}, "maximum of 10000 evaluation steps exceeded"},
+		{"while (true) { }", Config{MaxSteps: 100}, "maximum of 100 evaluation steps exceeded"},
+		{"let n = 0; while (n < 10) { n += 1 }; n", Config{MaxSteps: 100}, 10},
+		{"let a = []; while (true) { a = push(a, 1) }", Config{MaxObjects: 500}, "maximum of 500 objects exceeded"},
+		{"let a = 1; let b = a; a", Config{MaxObjects: 1}, 1},
+		{"[1, 2]", Config{MaxObjects: 2}, "maximum of 2 objects exceeded"},
+		{"let x = 2 ** 1000000; 1", Config{MaxSteps: 10000}, "maximum of 10000 evaluation steps exceeded"},
+		{"let x = 2 ** 1000000; 1", Config{MaxObjects: 10000}, "maximum of 10000 objects exceeded"},
+		{"let x = 2 ** 1000000; 1", Config{MaxSteps: 20000, MaxObjects: 20000}, 1},
+		{"while (true) { try { 1 } catch (e) { 2 } }", Config{MaxSteps: 100}, "maximum of 100 evaluation steps exceeded"},
+		{"let f = fn() { try { f() + 1 } catch (e) { 0 } }; f()", Config{MaxCallDepth: 20}, "maximum call depth of 20 exceeded"},
+		{"let f = fn() { try { 1 + f() } finally { return 0 } }; f()", Config{MaxCallDepth: 20}, "maximum call depth of 20 exceeded"},
+		{"let f = fn() { 1 + f() }; try { f() } catch (e) { 1 }", Config{MaxCallDepth: 20}, "maximum call depth of 20 exceeded"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		evaluated := NewWithConfig(tt.config).Eval(program, object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+			if !errObj.Fatal {
+				t.Errorf("%q: error is not fatal", tt.input)
+			}
+		}
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	tests := []struct {
+		input    string
+		timeout  time.Duration
+		expected interface{}
+	}{
+		{"let f = fn(n) { n * 2 }; f(21)", time.Second, 42},
+		{"while (true) { }", 20 * time.Millisecond, "evaluation cancelled: context deadline exceeded"},
+		{"for (i in 0..1000000000000) { }", 20 * time.Millisecond, "evaluation cancelled: context deadline exceeded"},
+		{"let f = fn() { f() }; f()", 20 * time.Millisecond, "evaluation cancelled: context deadline exceeded"},
+		{"let f = fn() { g() }; let g = fn() { f() }; f()", 20 * time.Millisecond, "evaluation cancelled: context deadline exceeded"},
+		{"while (true) { try { while (true) { } } catch (e) { } }", 20 * time.Millisecond, "evaluation cancelled: context deadline exceeded"},
+		{"let f = fn() { 1 }; f()", 0, "evaluation cancelled: context canceled"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+		if tt.timeout == 0 {
+			ctx, cancel = context.WithCancel(context.Background())
+			cancel()
+		}
+
+		start := time.Now()
+		evaluated := EvalContext(ctx, program, object.NewEnvironment())
+		cancel()
+
+		if elapsed := time.Since(start); elapsed > tt.timeout+time.Second {
+			t.Errorf("%q: evaluation was not stopped in time. took %s", tt.input, elapsed)
+		}
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+			if !errObj.Fatal {
+				t.Errorf("%q: error is not fatal", tt.input)
+			}
+		}
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"2 ** 64", "18446744073709551616"},
+		{"1 << 64", "18446744073709551616"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"123456789012345678901234567890", "123456789012345678901234567890"},
+		{"123456789012345678901234567890 * 10 + 1", "1234567890123456789012345678901"},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(25)", "15511210043330985984000000"},
+		{"~9223372036854775808", "-9223372036854775809"},
+		{"-9223372036854775808", -9223372036854775808},
+		{"(9223372036854775807 + 1) - 1", 9223372036854775807},
+		{"2 ** 64 / 2 ** 60", 16},
+		{"2 ** 64 % 10", 6},
+		{"(2 ** 64) >> 60", 16},
+		{"(2 ** 64 + 3) & 7", 3},
+		{"2 ** 64 > 2 ** 63", true},
+		{"2 ** 64 == 18446744073709551616", true},
+		{"2 ** 64 == 2 ** 63", false},
+		{"2 ** 64 < 1", false},
+		{"2 ** 64 * 0.5", 9223372036854775808.0},
+		{"2 ** 64 / 0", "division by zero"},
+		{"2 ** 64 % 0", "modulo by zero"},
+		{"2 ** 64 << -1", "negative shift count: -1"},
+		{"2 ** (2 ** 64)", "exponent too large: 18446744073709551616"},
+		{"2 ** 1048576", "integer too large: the result of ** would have up to 1048577 bits, the most is 1048576"},
+		{"len([2 ** 1000000 * 2 ** 48574])", 1},
+		{"len([3 ** 600000])", 1},
+		{"let x = 2 ** 1000000; x * x", "integer too large: the result of * would have up to 2000002 bits, the most is 1048576"},
+		{"1 << 1048576", "integer too large: the result of << would have up to 1048577 bits, the most is 1048576"},
+		{"0 << 1048576", 0},
+		{"(2 ** 64) ** 2 ** 63", "exponent too large: 9223372036854775808"},
+		{"{2 ** 64: 1, 2 ** 65: 2}[2 ** 65]", 2},
+		{"abs(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"int(1e20)", "100000000000000000000"},
+		{"float(2 ** 64)", 18446744073709551616.0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+				continue
+			}
+
+			bigInt, ok := evaluated.(*object.BigInt)
+			if !ok {
+				t.Errorf("object is not BigInt. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if bigInt.Inspect() != expected {
+				t.Errorf("object has wrong value. got=%s, want=%s", bigInt.Inspect(), expected)
+			}
+		}
+	}
+}
+
+func TestFunctionArity(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedPos     string
+	}{
+		{"fn(a, b) { a }(1)", "function expects 2 arguments, got 1", "1:1"},
+		{"let f = fn(x) { x };\nf(1, 2)", "function expects 1 argument, got 2", "2:1"},
+		{"fn() { 1 }(1)", "function expects 0 arguments, got 1", "1:1"},
+		{"let f = fn(a, b) { a };\nlet g = fn() { f(1) };\ng()", "function expects 2 arguments, got 1", "2:16"},
+		{"fn(a, b = 1) { a }(1, 2, 3)", "function expects 1 to 2 arguments, got 3", "1:1"},
+		{"fn(a, ...rest) { a }()", "function expects at least 1 argument, got 0", "1:1"},
+		{"fn(a = b) { a }()", "identifier not found: b", "1:8"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error position. expected=%s, got=%s", tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	input := `let inner = fn(x) {
+	x + true
+};
+let outer = fn() {
+	inner(1)
+};
+let run = fn(f) { f() };
+run(outer);`
+
+	evaluated := testEval(input)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if errObj.Pos.String() != "2:2" {
+		t.Errorf("wrong error position. want=2:2, got=%s", errObj.Pos)
+	}
+
+	expected := []string{
+		"inner(1) called at 5:2",
+		"outer() called at 7:19",
+		"run(<fn outer>) called at 8:1",
+	}
+
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("wrong stack length. want=%d, got=%d (%v)", len(expected), len(errObj.Stack), errObj.Stack)
+	}
+
+	for i, frame := range expected {
+		if errObj.Stack[i].String() != frame {
+			t.Errorf("wrong frame %d. want=%q, got=%q", i, frame, errObj.Stack[i].String())
+		}
+	}
+
+	traceback := "ERROR: type mismatch: INTEGER + BOOLEAN (at 2:2)\n" +
+		"    in inner(1) called at 5:2\n" +
+		"    in outer() called at 7:19\n" +
+		"    in run(<fn outer>) called at 8:1"
+	if errObj.Traceback() != traceback {
+		t.Errorf("wrong traceback.\nwant:\n%s\ngot:\n%s", traceback, errObj.Traceback())
+	}
+}
+
+func TestDeepErrorStackTrace(t *testing.T) {
+	evaluated := testEval(`let f = fn(n) { if (n == 0) { 1 / 0 } else { 1 + f(n - 1) } }; f(100)`)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(errObj.Stack) != 2*stackEnds {
+		t.Fatalf("wrong stack length. want=%d, got=%d", 2*stackEnds, len(errObj.Stack))
+	}
+	if errObj.Stack[stackEnds-1].Omitted != 101-2*stackEnds {
+		t.Errorf("wrong number of omitted calls. want=%d, got=%d", 101-2*stackEnds, errObj.Stack[stackEnds-1].Omitted)
+	}
+	if errObj.Stack[0].Args != "0" || errObj.Stack[len(errObj.Stack)-1].Args != "100" {
+		t.Errorf("wrong frames kept. got=%v", errObj.Stack)
+	}
+
+	if lines := strings.Count(errObj.Traceback(), "\n"); lines != 6 {
+		t.Errorf("wrong number of traceback lines. want=6, got=%d:\n%s", lines, errObj.Traceback())
+	}
+}
+
+func TestBuiltinErrorStackTrace(t *testing.T) {
+	evaluated := testEval(`let f = fn() { len(1) }; f()`)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(errObj.Stack) != 2 {
+		t.Fatalf("wrong stack length. want=2, got=%d (%v)", len(errObj.Stack), errObj.Stack)
+	}
+
+	if errObj.Stack[0].Function != "len" || errObj.Stack[1].Function != "f" {
+		t.Errorf("wrong frames. got=%v", errObj.Stack)
+	}
+}
+
+func TestStackTraceArgs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`f(1, "two", true)`, `1, "two", true`},
+		{`f("a very long string argument")`, `"a very long stri...`},
+		{`f([1, 2, 3], {"k": "v"})`, `[1, 2, 3], {k: v}`},
+		{`f([[[[[1]]]]])`, `[[[[...]]]]`},
+		{`f([1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12])`, `[1, 2, 3, 4, 5, 6...`},
+		{`f(fn(x) { x }, [f])`, `<fn>, [<fn f>]`},
+		{`let a = [0]; a[0] = a; f(a)`, `[[[[...]]]]`},
+		{`let h = {}; h["h"] = h; f(h)`, `{h: {h: {h: {...}}}}`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(`let f = fn(...args) { 1 / 0 }; ` + tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Stack[0].Args != tt.expected {
+			t.Errorf("%q: wrong arguments. want=%q, got=%q", tt.input, tt.expected, errObj.Stack[0].Args)
+		}
+	}
+
+	evaluated := testEval(`let a = [0]; a[0] = a; len(a)`)
+	testIntegerObject(t, evaluated, 1)
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -224,6 +815,238 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, ...rest] = [1, 2, 3]; a + len(rest)", 3},
+		{"let [a, ...rest] = [1]; len(rest)", 0},
+		{"let [_, b] = [1, 2]; b", 2},
+		{`let {"name": n, "age": a} = {"name": "x", "age": 30, "id": 7}; a`, 30},
+		{`let [x, {"v": [y]}] = [1, {"v": [2]}]; x + y`, 3},
+		{"let [1, b] = [1, 2]; b", 2},
+		{"let f = fn([a, b], c) { a + b + c }; f([1, 2], 3)", 6},
+		{`let f = fn({"x": x, "y": y}) { x * y }; f({"x": 3, "y": 4})`, 12},
+		{"let f = fn(a, [b, c] = [a, a]) { a + b + c }; f(1)", 3},
+		{"let [a, b] = [1, 2, 3]", "cannot destructure ARRAY into [a, b]: expected 2 elements, got 3"},
+		{"let [a, b, ...c] = [1]", "cannot destructure ARRAY into [a, b, ...c]: expected at least 2 elements, got 1"},
+		{"let [a] = 1", "cannot destructure INTEGER into [a]: expected ARRAY, got INTEGER"},
+		{`let {"age": a} = {"name": "x"}`, `cannot destructure HASH into {age:a}: missing key "age"`},
+		{`let {"a": a} = [1]`, "cannot destructure ARRAY into {a:a}: expected HASH, got ARRAY"},
+		{`let [{"a": a}] = [{1: 2}]`, "cannot destructure ARRAY into [{a:a}]: missing key \"a\""},
+		{"let [1, b] = [2, 3]", "cannot destructure ARRAY into [1, b]: expected 1, got 2"},
+		{"let f = fn([a, b]) { a }; f([1])", "cannot destructure ARRAY into [a, b]: expected 2 elements, got 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x += 2; x", 3},
+		{"let x = 1; x -= 2; x", -1},
+		{"let x = 3; x *= 2; x", 6},
+		{"let x = 9; x /= 2; x", 4},
+		{"let x = 1; let y = x = 5; x + y", 10},
+		{"let x = 1; x += 1", 2},
+		{"let makeCounter = fn() { let n = 0; fn() { n += 1 } }; let c = makeCounter(); c(); c(); c()", 3},
+		{"let x = 1; let f = fn() { x = 5 }; f(); x", 5},
+		{"let x = 1; let f = fn() { let x = 10; x = 20; x }; f() + x", 21},
+		{"let a = [1, 2, 3]; a[1] = 10; a[1]", 10},
+		{"let a = [1, 2, 3]; a[2] *= 4; a[2]", 12},
+		{`let h = {"a": 1}; h["b"] = 2; h["a"] + h["b"]`, 3},
+		{`let h = {"a": 1}; h["a"] += 5; h["a"]`, 6},
+		{"let m = [[1, 2], [3, 4]]; m[1][0] = 9; m[1][0]", 9},
+		{"x = 1", "assignment to undeclared identifier: x"},
+		{"let a = [1]; a[5] = 1", "index out of range: 5, length is 1"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+		{"let h = {}; h[fn() {}] = 1", "unusable as hash key: FUNCTION"},
+		{"let x = true; x += 1", "type mismatch: BOOLEAN + INTEGER"},
+		{`let h = {}; h["a"] += 1`, "type mismatch: NULL + INTEGER"},
+		{"let x = 1; x /= 0", "division by zero"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 10) { i += 1 }; i", 10},
+		{"let i = 0; while (false) { i += 1 }; i", 0},
+		{"let i = 0; while (true) { i += 1; if (i == 5) { break } }; i", 5},
+		{"let i = 0; let n = 0; while (i < 10) { i += 1; if (i % 2 == 0) { continue } n += 1 }; n", 5},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x }; sum", 6},
+		{"let sum = 0; for (i, x in [10, 20, 30]) { sum += i * x }; sum", 80},
+		{"let sum = 0; for (i in 0..100000) { sum += i }; sum", 4999950000},
+		{"let sum = 0; for (i in 5..3) { sum += 1 }; sum", 0},
+		{"let sum = 0; for (i, x in 5..8) { sum += i }; sum", 3},
+		{`let s = ""; for (ch in "héllo") { s = ch + s }; s`, "olléh"},
+		{`let s = ""; for (i, ch in "abc") { s += ch + i }`, "type mismatch: STRING + INTEGER"},
+		{`let s = ""; for (k in {"b": 2, "a": 1, "c": 3}) { s += k }; s`, "abc"},
+		{`let sum = 0; for (k, v in {"a": 1, "b": 2}) { sum += v }; sum`, 3},
+		{"let n = 0; for (k in {3: 1, 1: 2, 2: 3}) { n = n * 10 + k }; n", 123},
+		{"let n = 0; for (i in 0..10) { for (j in 0..10) { if (j == 2) { break } n += 1 } }; n", 20},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10 } } }; f()", 20},
+		{"let i = 0; while (true) { i = i + 1; let y = if (i > 3) { break; }; }; i", 4},
+		{"let i = 0; let y = 0; while (true) { i += 1; y = if (i > 3) { break } else { i }; }; y", 3},
+		{"let i = 0; let n = 0; while (i < 10) { i += 1; let y = if (i % 2 == 0) { continue } else { i }; n += y }; n", 25},
+		{"let a = [0]; for (x in 0..10) { a[0] = if (x == 4) { break } else { x } }; a[0]", 3},
+		{"let f = fn() { let y = if (true) { return 7 }; 1 }; f()", 7},
+		{"let a = [1]; for (x in a) { if (x < 5) { a[0] = 1; } }; len(a)", 1},
+		{"let i = 0; for (x in 1) { i += 1 }", "cannot iterate over INTEGER"},
+		{"let i = 0; while (undefined) { i += 1 }", "identifier not found: undefined"},
+		{"for (x in [1]) { let y = x }; y", "identifier not found: y"},
+		{"let r = 1..3; r", "1..3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			case *object.Range:
+				if obj.Inspect() != expected {
+					t.Errorf("wrong range. expected=%q, got=%q", expected, obj.Inspect())
+				}
+			default:
+				t.Errorf("unexpected object. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let loop = fn(n) { if (n == 0) { return "done" } loop(n - 1) }; loop(300000)`, "done"},
+		{"let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(100000, 0)", 5000050000},
+		{"let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); }; sum(100000, 0)", 5000050000},
+		{"let count = fn(n) { match (n) { 0 => 42, _ => count(n - 1) } }; count(100000)", 42},
+		{`
+		let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+		let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+		if (isEven(100001)) { 1 } else { 0 }`, 0},
+		{"let down = fn(n) { if (n == 0) { 0 } else { 1 + down(n - 1) } }; down(1000)", 1000},
+		{`let g = fn() { throw "x" }; let f = fn() { try { g() } catch (e) { 7 } }; f()`, 7},
+		{"let f = fn(n) { let m = n * 2; if (m > 10) { m } else { f(m) } }; f(1)", 16},
+		{"let f = fn(n) { if (n == 0) { 1 + true } else { f(n - 1) } }; f(10000)", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn(n) { if (n == 0) { g(1, 2) } else { f(n - 1) } }; let g = fn(a) { a }; f(3)", "function expects 1 argument, got 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, obj.Message)
+				}
+			default:
+				t.Errorf("%q: object is not String or Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestTailCallStackTrace(t *testing.T) {
+	input := `let loop = fn(n) {
+	if (n == 0) { g(n) } else { loop(n - 1) }
+};
+let g = fn(x) { x + true };
+loop(1000);`
+
+	evaluated := testEval(input)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := []string{
+		"g(0) called at 2:16",
+		"loop(0) called at 2:30",
+		"loop(1) called at 2:30",
+		"loop(2) called at 2:30",
+		"loop(3) called at 2:30",
+		"loop(4) called at 2:30",
+		"loop(5) called at 2:30",
+		"loop(6) called at 2:30 (993 tail calls elided)",
+		"loop(1000) called at 5:1",
+	}
+
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("wrong stack length. want=%d, got=%d (%v)", len(expected), len(errObj.Stack), errObj.Stack)
+	}
+
+	for i, frame := range expected {
+		if errObj.Stack[i].String() != frame {
+			t.Errorf("wrong frame %d. want=%q, got=%q", i, frame, errObj.Stack[i].String())
+		}
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; }"
 
@@ -248,6 +1071,21 @@ func TestFunctionObject(t *testing.T) {
 	}
 }
 
+func TestFunctionObjectInspect(t *testing.T) {
+	input := "fn(x, y = 10, ...rest) { x }"
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := "fn(x, y = 10, ...rest) {\nx\n}"
+	if fn.Inspect() != expected {
+		t.Errorf("Inspect() wrong. want=%q, got=%q", expected, fn.Inspect())
+	}
+}
+
 func TestFunctionApplication(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -259,6 +1097,14 @@ func TestFunctionApplication(t *testing.T) {
 		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
 		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
 		{"fn(x) { x; }(5)", 5},
+		{"let f = fn(x, y = 10) { x + y }; f(1)", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2)", 3},
+		{"let f = fn(x, y = x * 2) { x + y }; f(3)", 9},
+		{"let f = fn(x = later) { x }; let later = 7; f()", 7},
+		{"let f = fn(x, ...rest) { len(rest) }; f(1, 2, 3)", 2},
+		{"let f = fn(x, ...rest) { len(rest) }; f(1)", 0},
+		{"let f = fn(...rest) { rest[1] }; f(1, 2, 3)", 2},
+		{"let f = fn(x = 1, ...rest) { x + len(rest) }; f()", 1},
 	}
 
 	for _, tt := range tests {
@@ -293,6 +1139,20 @@ func TestStringLiteral(t *testing.T) {
 	}
 }
 
+func TestStringEscapes(t *testing.T) {
+	input := `"tab\tquote\"newline\n" + "\u{263A}"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "tab\tquote\"newline\n\u263A" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!"`
 
@@ -315,8 +1175,34 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo, 世界")`, 9},
 		{`len(1)`, "argument to `len` not supported, got=INTEGER"},
-		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`len("one", "two")`, "`len` expects 1 argument, got 2"},
+		{`abs(-3)`, 3},
+		{`abs(-2.5)`, 2.5},
+		{`floor(2.7)`, 2.0},
+		{`ceil(2.2)`, 3.0},
+		{`round(2.5)`, 3.0},
+		{`floor(7)`, 7},
+		{`sqrt(16)`, 4.0},
+		{`pow(2, 0.5) * pow(2, 0.5)`, 2.0000000000000004},
+		{`min(3, 1.5, 2)`, 1.5},
+		{`max(3, 1.5, 2)`, 3},
+		{`int(-2.9)`, -2},
+		{`float(3)`, 3.0},
+		{`sqrt("4")`, "argument to `sqrt` must be INTEGER or FLOAT. got STRING"},
+		{`min()`, "`min` expects at least 1 argument, got 0"},
+		{`push([])`, "`push` expects 2 arguments, got 1"},
+		{`slice([1])`, "`slice` expects 2 to 3 arguments, got 1"},
+		{`floor(1, 2)`, "`floor` expects 1 argument, got 2"},
+		{`int(1 / 0.0)`, "float +Inf out of range for INTEGER"},
+		{`slice("héllo, 世界", 7)`, "世界"},
+		{`slice("héllo", 1, 3)`, "él"},
+		{`slice("héllo", -3)`, "llo"},
+		{`slice("héllo", 4, 1)`, ""},
+		{`len(slice([1, 2, 3, 4], 1, -1))`, 2},
+		{`slice([1, 2, 3], 1)[0]`, 2},
+		{`slice(1, 2)`, "argument to `slice` must be ARRAY or STRING. got INTEGER"},
 	}
 
 	for _, tt := range tests {
@@ -325,7 +1211,16 @@ func TestBuiltinFunctions(t *testing.T) {
 		switch expected := tt.expected.(type) {
 		case int:
 			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
 		case string:
+			if str, ok := evaluated.(*object.String); ok {
+				if str.Value != expected {
+					t.Errorf("wrong string. Expected=%q, got=%q", expected, str.Value)
+				}
+				continue
+			}
+
 			errObj, ok := evaluated.(*object.Error)
 			if !ok {
 				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
@@ -414,6 +1309,77 @@ func TestArrayIndexExpressons(t *testing.T) {
 	}
 }
 
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"héllo"[1]`, "é"},
+		{`"世界"[1]`, "界"},
+		{`let s = "abc"; s[len(s) - 1]`, "c"},
+		{`"abc"[3]`, nil},
+		{`"abc"[-1]`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		expected, ok := tt.expected.(string)
+		if !ok {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != expected {
+			t.Errorf("String has wrong value. want=%q, got=%q", expected, str.Value)
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(a, b, c) { a + b + c }; let xs = [1, 2, 3]; f(...xs)", 6},
+		{"let f = fn(a, b, c) { a * b + c }; f(2, ...[3, 4])", 10},
+		{"fn(...rest) { len(rest) }(...[], ...[1, 2])", 2},
+		{"len([1, ...[2, 3], 4])", 4},
+		{"[1, ...[2, 3], 4][2]", 3},
+		{`let base = {"a": 1, "b": 2}; {...base, "b": 3}["b"]`, 3},
+		{`let base = {"a": 1, "b": 2}; {"b": 3, ...base}["b"]`, 2},
+		{`let base = {"a": 1}; {...base, "c": 3}["a"]`, 1},
+		{"let f = fn(x) { x }; f(...1)", "cannot spread INTEGER, expected ARRAY"},
+		{"[...{}]", "cannot spread HASH, expected ARRAY"},
+		{"{...[1]}", "cannot spread ARRAY into a hash, expected HASH"},
+		{"len(...[1, 2])", "`len` expects 1 argument, got 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -526,6 +1492,22 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
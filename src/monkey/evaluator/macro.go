@@ -0,0 +1,217 @@
+package evaluator
+
+import (
+	"math/big"
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+	"strconv"
+)
+
+// DefineMacros removes the top-level let statements that bind a macro
+// literal from program, and defines their macros in env instead
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	New().DefineMacros(program, env)
+}
+
+// ExpandMacros replaces the calls to the macros defined in env in program
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	return New().ExpandMacros(program, env)
+}
+
+// DefineMacros removes the top-level let statements that bind a macro
+// literal from program, and defines their macros in env instead
+func (e *Evaluator) DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := []ast.Statement{}
+
+	for _, statement := range program.Statements {
+		if let, ok := statement.(*ast.LetStatement); ok && let.Name != nil {
+			if lit, ok := let.Value.(*ast.MacroLiteral); ok {
+				env.Set(let.Name.Value, &object.Macro{
+					Parameters: lit.Parameters,
+					Body:       lit.Body,
+					Env:        env,
+				})
+				continue
+			}
+		}
+
+		statements = append(statements, statement)
+	}
+
+	program.Statements = statements
+}
+
+// ExpandMacros returns a copy of program in which every call to a macro
+// defined in env is replaced by the AST the macro returns. The macro gets the
+// arguments of the call unevaluated, as quotes.
+func (e *Evaluator) ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil {
+			return node
+		}
+
+		macro, name, ok := lookupMacro(call, env)
+		if !ok {
+			return node
+		}
+
+		result := e.applyMacro(call, name, macro)
+		if quote, ok := result.(*object.Quote); ok {
+			return quote.Node
+		}
+
+		if err, ok = result.(*object.Error); ok {
+			return node
+		}
+
+		got := "nothing"
+		if result != nil {
+			got = string(result.Type())
+		}
+		err = newError("macro %s must return a quote, got %s", name, got)
+		e.annotate(err, call.Pos())
+		return node
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+func lookupMacro(call *ast.CallExpression, env *object.Environment) (*object.Macro, string, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, "", false
+	}
+
+	obj, ok := env.Get(ident.Value)
+	if !ok {
+		return nil, "", false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	return macro, ident.Value, ok
+}
+
+// applyMacro evaluates the body of macro, with its parameters bound to the
+// quoted arguments of call
+func (e *Evaluator) applyMacro(call *ast.CallExpression, name string, macro *object.Macro) object.Object {
+	args := make([]object.Object, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		args[i] = &object.Quote{Node: arg}
+	}
+
+	fn := &object.Function{
+		Name:       name,
+		Parameters: macro.Parameters,
+		Body:       macro.Body,
+		Env:        macro.Env,
+	}
+
+	if err := e.pushFrame(call, fn, args); err != nil {
+		return err
+	}
+	defer e.popFrame()
+
+	var result object.Object
+	min, max := functionArity(fn)
+	if err := checkArity("macro", len(args), min, max); err != nil {
+		result = err
+	} else if env, err := e.extendFunctionEnv(fn, args); err != nil {
+		result = err
+	} else {
+		result = unwrapReturnValue(e.Eval(fn.Body, env))
+	}
+
+	if err, ok := result.(*object.Error); ok {
+		e.annotate(err, call.Pos())
+	}
+	return result
+}
+
+// quote returns node as a Quote, with every unquote(x) call in it replaced by
+// the AST for the value of x
+func (e *Evaluator) quote(node ast.Node, env *object.Environment) object.Object {
+	var err object.Object
+
+	node = ast.Modify(node, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil {
+			return node
+		}
+
+		if ident, ok := call.Function.(*ast.Identifier); !ok || ident.Value != "unquote" {
+			return node
+		}
+
+		if arityErr := checkArity("`unquote`", len(call.Arguments), 1, 1); arityErr != nil {
+			err = arityErr
+			return node
+		}
+
+		value := e.Eval(call.Arguments[0], env)
+		if isError(value) {
+			err = value
+			return node
+		}
+
+		unquoted, ok := objectToNode(value, call)
+		if !ok {
+			err = newError("cannot unquote %s", value.Type())
+			return node
+		}
+		return unquoted
+	})
+
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+
+// objectToNode converts obj into the literal that evaluates to it, placed
+// where the node at is
+func objectToNode(obj object.Object, at ast.Node) (ast.Node, bool) {
+	tok := token.Token{Pos: at.Pos(), End: at.End()}
+
+	switch obj := obj.(type) {
+	case *object.Quote:
+		return obj.Node, true
+
+	case *object.Integer:
+		tok.Type, tok.Literal = token.INT, strconv.FormatInt(obj.Value, 10)
+		return &ast.IntegerLiteral{Token: tok, Value: obj.Value}, true
+
+	case *object.BigInt:
+		tok.Type, tok.Literal = token.INT, obj.Value.String()
+		return &ast.BigIntegerLiteral{Token: tok, Value: new(big.Int).Set(obj.Value)}, true
+
+	case *object.Float:
+		tok.Type, tok.Literal = token.FLOAT, obj.Inspect()
+		return &ast.FloatLiteral{Token: tok, Value: obj.Value}, true
+
+	case *object.Boolean:
+		tok.Type, tok.Literal = token.FALSE, "false"
+		if obj.Value {
+			tok.Type, tok.Literal = token.TRUE, "true"
+		}
+		return &ast.Boolean{Token: tok, Value: obj.Value}, true
+
+	case *object.String:
+		tok.Type, tok.Literal = token.STRING, obj.Value
+		return &ast.StringLiteral{Token: tok, Value: obj.Value}, true
+
+	default:
+		return nil, false
+	}
+}
+
+func isQuoteCall(call *ast.CallExpression) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == "quote"
+}
@@ -0,0 +1,105 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"sort"
+)
+
+// Builtins is a registry of the builtin functions an Evaluator resolves
+// identifiers to when no variable has their name. Functions can also be
+// grouped into namespaces, which code sees as a hash of their functions and
+// calls like strings["upper"]("a").
+type Builtins struct {
+	prefix     string // prepended to the names of the functions, for namespaces
+	functions  map[string]*object.Builtin
+	namespaces map[string]*Builtins
+}
+
+// NewBuiltins creates an empty registry, for an Evaluator that should not be
+// able to call any builtin function it has not been given explicitly
+func NewBuiltins() *Builtins {
+	return &Builtins{
+		functions:  make(map[string]*object.Builtin),
+		namespaces: make(map[string]*Builtins),
+	}
+}
+
+// DefaultBuiltins creates a registry with the standard builtin functions,
+// such as len, push and puts. Each call returns a new registry, so changing
+// one does not affect any other.
+func DefaultBuiltins() *Builtins {
+	b := NewBuiltins()
+	for name, builtin := range defaultBuiltins {
+		b.Register(name, builtin.Fn)
+	}
+	return b
+}
+
+// Register adds fn to the registry as name, replacing any function or
+// namespace already registered under that name
+func (b *Builtins) Register(name string, fn object.BuiltinFunction) {
+	delete(b.namespaces, name)
+	b.functions[name] = &object.Builtin{Name: b.prefix + name, Fn: fn}
+}
+
+// Namespace returns the namespace registered as name, creating it if there is
+// none. Creating it replaces any function registered under that name.
+func (b *Builtins) Namespace(name string) *Builtins {
+	if ns, ok := b.namespaces[name]; ok {
+		return ns
+	}
+
+	delete(b.functions, name)
+
+	ns := NewBuiltins()
+	ns.prefix = b.prefix + name + "."
+	b.namespaces[name] = ns
+	return ns
+}
+
+// Remove removes the function or namespace registered as name, if any
+func (b *Builtins) Remove(name string) {
+	delete(b.functions, name)
+	delete(b.namespaces, name)
+}
+
+// Names returns the names of the functions and namespaces in the registry,
+// in sorted order
+func (b *Builtins) Names() []string {
+	names := make([]string, 0, len(b.functions)+len(b.namespaces))
+	for name := range b.functions {
+		names = append(names, name)
+	}
+	for name := range b.namespaces {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns what name refers to: a *object.Builtin for a function, or
+// an *object.Hash of its contents for a namespace
+func (b *Builtins) Lookup(name string) (object.Object, bool) {
+	if fn, ok := b.functions[name]; ok {
+		return fn, true
+	}
+
+	if ns, ok := b.namespaces[name]; ok {
+		return ns.hash(), true
+	}
+
+	return nil, false
+}
+
+func (b *Builtins) hash() *object.Hash {
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+
+	for _, name := range b.Names() {
+		value, _ := b.Lookup(name)
+		key := &object.String{Value: name}
+		hash.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return hash
+}
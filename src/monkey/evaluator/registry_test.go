@@ -0,0 +1,118 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinsRegistry(t *testing.T) {
+	upper := func(args ...object.Object) object.Object {
+		return &object.String{Value: strings.ToUpper(args[0].Inspect())}
+	}
+	answer := func(args ...object.Object) object.Object {
+		return &object.Integer{Value: 42}
+	}
+
+	tests := []struct {
+		setup    func(b *Builtins)
+		input    string
+		expected interface{}
+	}{
+		{func(b *Builtins) {}, `len("abc")`, 3},
+		{func(b *Builtins) { b.Register("answer", answer) }, `answer()`, 42},
+		{func(b *Builtins) { b.Register("len", answer) }, `len("abc")`, 42},
+		{func(b *Builtins) { b.Remove("len") }, `len("abc")`, "identifier not found: len"},
+		{func(b *Builtins) { b.Namespace("strings").Register("upper", upper) }, `strings["upper"]("abc")`, "ABC"},
+		{func(b *Builtins) { b.Namespace("a").Namespace("b").Register("answer", answer) }, `a["b"]["answer"]()`, 42},
+		{func(b *Builtins) { b.Namespace("strings").Register("upper", upper) }, `len(strings)`, "argument to `len` not supported, got=HASH"},
+		{func(b *Builtins) { b.Namespace("len") }, `len("abc")`, "not a function: HASH"},
+		{func(b *Builtins) { b.Namespace("answer"); b.Register("answer", answer) }, `answer()`, 42},
+		{func(b *Builtins) { b.Register("answer", answer) }, `let answer = 1; answer`, 1},
+	}
+
+	for _, tt := range tests {
+		builtins := DefaultBuiltins()
+		tt.setup(builtins)
+
+		e := NewWithConfig(Config{Builtins: builtins})
+		evaluated := e.Eval(testParseProgram(tt.input), object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch evaluated := evaluated.(type) {
+			case *object.String:
+				if evaluated.Value != expected {
+					t.Errorf("%q: wrong value. expected=%q, got=%q", tt.input, expected, evaluated.Value)
+				}
+			case *object.Error:
+				if evaluated.Message != expected {
+					t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, evaluated.Message)
+				}
+			default:
+				t.Errorf("%q: unexpected object. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestEmptyBuiltins(t *testing.T) {
+	e := NewWithConfig(Config{Builtins: NewBuiltins()})
+
+	for _, input := range []string{`len("a")`, `puts(1)`} {
+		evaluated := e.Eval(testParseProgram(input), object.NewEnvironment())
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || !strings.HasPrefix(errObj.Message, "identifier not found: ") {
+			t.Errorf("%q: expected an identifier not found error. got=%T (%+v)", input, evaluated, evaluated)
+		}
+	}
+
+	if names := NewBuiltins().Names(); len(names) != 0 {
+		t.Errorf("expected no builtins. got=%v", names)
+	}
+}
+
+func TestDefaultBuiltinsAreIndependent(t *testing.T) {
+	first := New()
+	first.Builtins().Remove("len")
+	first.Builtins().Namespace("extra")
+
+	second := New()
+	if _, ok := second.Builtins().Lookup("len"); !ok {
+		t.Errorf("removing len from one evaluator removed it from another")
+	}
+	if _, ok := second.Builtins().Lookup("extra"); ok {
+		t.Errorf("a namespace added to one evaluator was added to another")
+	}
+
+	names := second.Builtins().Names()
+	if len(names) != len(defaultBuiltins) {
+		t.Errorf("wrong number of names. want=%d, got=%d", len(defaultBuiltins), len(names))
+	}
+	if !reflect.DeepEqual(names[:2], []string{"abs", "ceil"}) {
+		t.Errorf("names are not sorted. got=%v", names)
+	}
+}
+
+func TestNamespacedBuiltinStackTrace(t *testing.T) {
+	builtins := DefaultBuiltins()
+	builtins.Namespace("math").Register("fail", func(args ...object.Object) object.Object {
+		return newError("failed")
+	})
+
+	e := NewWithConfig(Config{Builtins: builtins})
+	evaluated := e.Eval(testParseProgram(`let f = fn() { math["fail"]() }; f()`), object.NewEnvironment())
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(errObj.Stack) != 2 || errObj.Stack[0].Function != "math.fail" || errObj.Stack[1].Function != "f" {
+		t.Errorf("wrong frames. got=%v", errObj.Stack)
+	}
+}
@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// tailCall is a call in tail position of a function body. It is returned to
+// applyFunction, which makes the call once the body has been left.
+type tailCall struct {
+	call *ast.CallExpression
+	fn   object.Object
+	args []object.Object
+}
+
+// Type for tailCall
+func (tc *tailCall) Type() object.ObjectType {
+	return "TAIL_CALL"
+}
+
+// Inspect for tailCall
+func (tc *tailCall) Inspect() string {
+	return "tail call to " + tc.call.Function.String()
+}
+
+// evalTail evaluates node, which is in tail position of a function body: its
+// value is what the function returns. A call there is not made, but returned
+// as a tailCall. Calls reached through the branches of an if or a match, or
+// through a return, are in tail position too.
+func (e *Evaluator) evalTail(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if len(node.Statements) == 0 {
+			return nil
+		}
+
+		last := len(node.Statements) - 1
+		for _, statement := range node.Statements[:last] {
+			if result := e.Eval(statement, env); endsBlock(result) {
+				return result
+			}
+		}
+
+		return e.evalTail(node.Statements[last], env)
+
+	case *ast.ExpressionStatement:
+		return e.evalTail(node.Expression, env)
+
+	case *ast.ReturnStatement:
+		val := e.evalTail(node.ReturnValue, env)
+		switch val.(type) {
+		case *tailCall, *object.Error:
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+
+	case *ast.IfExpression:
+		condition := e.Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		switch {
+		case isTruthy(condition):
+			return e.evalTail(node.Consequence, env)
+		case node.Alternative != nil:
+			return e.evalTail(node.Alternative, env)
+		default:
+			return NULL
+		}
+
+	case *ast.MatchExpression:
+		arm, armEnv, err := e.selectMatchArm(node, env)
+		if err != nil {
+			if err, ok := err.(*object.Error); ok {
+				e.annotate(err, node.Pos())
+			}
+			return err
+		}
+		return e.evalTail(arm.Body, armEnv)
+
+	case *ast.CallExpression:
+		if isQuoteCall(node) {
+			return e.Eval(node, env)
+		}
+
+		function, args, err := e.evalCall(node, env)
+		if err != nil {
+			return err
+		}
+		return &tailCall{call: node, fn: function, args: args}
+
+	default:
+		return e.Eval(node, env)
+	}
+}
@@ -0,0 +1,191 @@
+package monkey
+
+import (
+	"fmt"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+)
+
+// Interpreter runs Monkey code on behalf of a Go program. Its globals persist
+// from one call to the next, so a function defined by one Run can be called
+// by a later one. An Interpreter is not safe for concurrent use; a host that
+// runs code from several goroutines needs an Interpreter for each, or to
+// serialize the calls itself.
+type Interpreter struct {
+	evaluator *evaluator.Evaluator
+	env       *object.Environment
+	macroEnv  *object.Environment
+}
+
+// New creates an Interpreter with the default configuration
+func New() *Interpreter {
+	return NewWithConfig(evaluator.Config{})
+}
+
+// NewWithConfig creates an Interpreter that evaluates with the given
+// configuration
+func NewWithConfig(config evaluator.Config) *Interpreter {
+	return &Interpreter{
+		evaluator: evaluator.NewWithConfig(config),
+		env:       object.NewEnvironment(),
+		macroEnv:  object.NewEnvironment(),
+	}
+}
+
+// Run evaluates src, and returns the value of its last statement converted
+// by FromObject
+func (i *Interpreter) Run(src string) (interface{}, error) {
+	return i.run(lexer.New(src), src)
+}
+
+// RunFile evaluates the script at path, like Run
+func (i *Interpreter) RunFile(path string) (interface{}, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.run(lexer.NewFile(path, string(src)), string(src))
+}
+
+func (i *Interpreter) run(l *lexer.Lexer, src string) (interface{}, error) {
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		return nil, &ParseError{Source: src, Diagnostics: p.Diagnostics()}
+	}
+
+	i.evaluator.DefineMacros(program, i.macroEnv)
+	expanded, err := i.evaluator.ExpandMacros(program, i.macroEnv)
+	if err != nil {
+		return nil, &RuntimeError{Err: err}
+	}
+
+	return result(i.evaluator.Eval(expanded, i.env))
+}
+
+// Call calls the global function name with args converted by ToObject, and
+// returns its result converted by FromObject
+func (i *Interpreter) Call(name string, args ...interface{}) (interface{}, error) {
+	fn, ok := i.env.Get(name)
+	if !ok {
+		fn, ok = i.evaluator.Builtins().Lookup(name)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s is not defined", name)
+	}
+
+	switch fn.(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return nil, fmt.Errorf("%s is not a function, got %s", name, fn.Type())
+	}
+
+	objects := make([]object.Object, len(args))
+	for idx, arg := range args {
+		obj, err := ToObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d of %s: %s", idx+1, name, err)
+		}
+		objects[idx] = obj
+	}
+
+	return result(i.evaluator.Call(fn, objects))
+}
+
+// Set binds the global name to value converted by ToObject
+func (i *Interpreter) Set(name string, value interface{}) error {
+	obj, err := ToObject(value)
+	if err != nil {
+		return err
+	}
+
+	i.env.Set(name, obj)
+	return nil
+}
+
+// Get returns the value of the global name converted by FromObject
+func (i *Interpreter) Get(name string) (interface{}, error) {
+	obj, ok := i.env.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%s is not defined", name)
+	}
+
+	return FromObject(obj)
+}
+
+// Register adds fn to the builtin functions as name. The arguments it is
+// called with are converted by FromObject, and the value it returns by
+// ToObject; an error it returns is raised in the calling code.
+func (i *Interpreter) Register(name string, fn func(args ...interface{}) (interface{}, error)) {
+	i.evaluator.Builtins().Register(name, wrapFunc(fn))
+}
+
+// Builtins returns the registry of builtin functions the Interpreter uses,
+// for registering functions in namespaces or removing them
+func (i *Interpreter) Builtins() *evaluator.Builtins {
+	return i.evaluator.Builtins()
+}
+
+func wrapFunc(fn func(args ...interface{}) (interface{}, error)) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		values := make([]interface{}, len(args))
+		for idx, arg := range args {
+			value, err := FromObject(arg)
+			if err != nil {
+				return &object.Error{Message: err.Error()}
+			}
+			values[idx] = value
+		}
+
+		value, err := fn(values...)
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+
+		obj, err := ToObject(value)
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		return obj
+	}
+}
+
+func result(obj object.Object) (interface{}, error) {
+	if err, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Err: err}
+	}
+
+	return FromObject(obj)
+}
+
+// ParseError reports the problems the parser found in some source
+type ParseError struct {
+	Source      string
+	Diagnostics []parser.Diagnostic
+}
+
+func (e *ParseError) Error() string {
+	msg := e.Diagnostics[0].String()
+	if n := len(e.Diagnostics) - 1; n > 0 {
+		msg += fmt.Sprintf(" (and %d more)", n)
+	}
+	return msg
+}
+
+// RuntimeError is an error raised while evaluating Monkey code. Err.Traceback
+// describes where it occurred.
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	if !e.Err.Pos.IsValid() {
+		return e.Err.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Err.Pos, e.Err.Message)
+}
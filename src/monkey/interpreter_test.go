@@ -0,0 +1,311 @@
+package monkey
+
+import (
+	"errors"
+	"math/big"
+	"monkey/evaluator"
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`1 + 2`, int64(3)},
+		{`"foo" + "bar"`, "foobar"},
+		{`1 < 2`, true},
+		{`1.5 * 2.0`, 3.0},
+		{`if (false) { 1 }`, nil},
+		{`let x = 1;`, nil},
+		{`[1, "two", [true]]`, []interface{}{int64(1), "two", []interface{}{true}}},
+		{`{"a": 1, "b": [2]}`, map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2)}}},
+		{`{1: "one", true: "yes"}`, map[interface{}]interface{}{int64(1): "one", true: "yes"}},
+		{`9223372036854775807 + 1`, new(big.Int).Lsh(big.NewInt(1), 63)},
+		{`let unless = macro(c, x) { quote(if (!(unquote(c))) { unquote(x) }) }; unless(false, 7)`, int64(7)},
+	}
+
+	for _, tt := range tests {
+		result, err := New().Run(tt.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: wrong result. expected=%#v, got=%#v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let = 1`, "1:5: expected next token to be IDENT, got = instead"},
+		{`let = 1; let = 2`, "1:5: expected next token to be IDENT, got = instead (and 1 more)"},
+		{`1 / 0`, "1:1: division by zero"},
+		{`let m = macro() { 1 }; m()`, "1:24: macro m must return a quote, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		_, err := New().Run(tt.input)
+		if err == nil {
+			t.Errorf("%q: expected an error, got none", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+
+	_, err := New().Run(`let`)
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Source != `let` {
+		t.Errorf("expected a *ParseError for the source. got=%T (%+v)", err, err)
+	}
+
+	_, err = New().Run(`throw "boom"`)
+	if runtimeErr, ok := err.(*RuntimeError); !ok || runtimeErr.Err.Message != "boom" {
+		t.Errorf("expected a *RuntimeError for boom. got=%T (%+v)", err, err)
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.mk")
+	if err := os.WriteFile(path, []byte("let x = 2;\nx * 3 / 0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := New().RunFile(path)
+	if err == nil || err.Error() != path+":2:1: division by zero" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	if _, err := New().RunFile(filepath.Join(t.TempDir(), "missing.mk")); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error. got=%v", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	interp := New()
+
+	_, err := interp.Run(`
+	let add = fn(a, b) { a + b };
+	let keys = fn(h) { h["x"] };
+	let count = fn(xs) { len(xs) };
+	let fail = fn() { 1 / 0 };
+	let n = 1;
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []interface{}
+		expected interface{}
+	}{
+		{"add", []interface{}{1, 2}, int64(3)},
+		{"add", []interface{}{"a", "b"}, "ab"},
+		{"add", []interface{}{int8(1), uint(2)}, int64(3)},
+		{"add", []interface{}{1.5, float32(1)}, 2.5},
+		{"keys", []interface{}{map[string]int{"x": 5}}, int64(5)},
+		{"count", []interface{}{[]string{"a", "b", "c"}}, int64(3)},
+		{"count", []interface{}{[2]bool{}}, int64(2)},
+		{"len", []interface{}{"four"}, int64(4)},
+		{"add", []interface{}{1}, "function expects 2 arguments, got 1"},
+		{"fail", nil, "5:20: division by zero"},
+		{"n", nil, "n is not a function, got INTEGER"},
+		{"missing", nil, "missing is not defined"},
+		{"count", []interface{}{struct{}{}}, "argument 1 of count: cannot convert struct {} to a Monkey value"},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Call(tt.name, tt.args...)
+
+		if expected, ok := tt.expected.(string); ok && err != nil {
+			if err.Error() != expected {
+				t.Errorf("%s%v: wrong error. expected=%q, got=%q", tt.name, tt.args, expected, err.Error())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s%v: unexpected error: %s", tt.name, tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%s%v: wrong result. expected=%#v, got=%#v", tt.name, tt.args, tt.expected, result)
+		}
+	}
+
+	_, err = interp.Call("fail")
+	traceback := err.(*RuntimeError).Err.Traceback()
+	if !strings.Contains(traceback, "called at <host>") {
+		t.Errorf("traceback does not show the host call. got=%q", traceback)
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	interp := New()
+
+	if err := interp.Set("xs", []int{1, 2, 3}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := interp.Set("nothing", nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := interp.Set("bad", make(chan int)); err == nil {
+		t.Errorf("expected an error for a channel")
+	}
+	if err := interp.Set("bad", map[interface{}]int{nil: 1}); err == nil || err.Error() != "unusable as hash key: NULL" {
+		t.Errorf("wrong error for an unhashable key. got=%v", err)
+	}
+
+	result, err := interp.Run(`let total = 0; for (x in xs) { total += x }; total`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result != int64(6) {
+		t.Errorf("wrong result. got=%#v", result)
+	}
+
+	if total, err := interp.Get("total"); err != nil || total != int64(6) {
+		t.Errorf("wrong value for total. got=%#v (%v)", total, err)
+	}
+	if nothing, err := interp.Get("nothing"); err != nil || nothing != nil {
+		t.Errorf("wrong value for nothing. got=%#v (%v)", nothing, err)
+	}
+	if _, err := interp.Get("missing"); err == nil || err.Error() != "missing is not defined" {
+		t.Errorf("wrong error for missing. got=%v", err)
+	}
+
+	interp.Run(`let f = fn() { 1 }`)
+	if f, _ := interp.Get("f"); reflect.TypeOf(f) != reflect.TypeOf(&object.Function{}) {
+		t.Errorf("expected a function to be returned as it is. got=%T", f)
+	}
+}
+
+func TestCyclicValues(t *testing.T) {
+	interp := New()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = [0]; a[0] = a; a`, "cannot convert an ARRAY that contains itself"},
+		{`let h = {}; h["self"] = [h]; h`, "cannot convert a HASH that contains itself"},
+	}
+
+	for _, tt := range tests {
+		_, err := interp.Run(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	if _, err := interp.Get("a"); err == nil {
+		t.Errorf("expected an error for a cyclic global")
+	}
+
+	interp.Register("id", func(args ...interface{}) (interface{}, error) {
+		return args[0], nil
+	})
+	if _, err := interp.Run(`id(a)`); err == nil || err.Error() != "1:1: cannot convert an ARRAY that contains itself" {
+		t.Errorf("wrong error for a cyclic argument. got=%v", err)
+	}
+
+	cyclicSlice := []interface{}{1, nil}
+	cyclicSlice[1] = cyclicSlice
+	if err := interp.Set("s", cyclicSlice); err == nil || err.Error() != "cannot convert cyclic value of type []interface {}" {
+		t.Errorf("wrong error for a cyclic slice. got=%v", err)
+	}
+
+	cyclicMap := map[string]interface{}{"a": 1}
+	cyclicMap["self"] = []interface{}{cyclicMap}
+	if _, err := interp.Call("id", cyclicMap); err == nil || err.Error() != "argument 1 of id: cannot convert cyclic value of type map[string]interface {}" {
+		t.Errorf("wrong error for a cyclic map. got=%v", err)
+	}
+
+	sharedSlice := []interface{}{1}
+	if err := interp.Set("s", []interface{}{sharedSlice, sharedSlice, map[string]interface{}{"s": sharedSlice}}); err != nil {
+		t.Errorf("unexpected error for a shared slice: %s", err)
+	}
+
+	result, err := interp.Run(`let x = [1]; [x, x, {"x": x}]`)
+	if err != nil {
+		t.Fatalf("unexpected error for a shared value: %s", err)
+	}
+	shared := []interface{}{int64(1)}
+	if expected := []interface{}{shared, shared, map[string]interface{}{"x": shared}}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("wrong result. expected=%#v, got=%#v", expected, result)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	interp := New()
+
+	interp.Register("sum", func(args ...interface{}) (interface{}, error) {
+		var total int64
+		for _, arg := range args[0].([]interface{}) {
+			n, ok := arg.(int64)
+			if !ok {
+				return nil, errors.New("sum expects integers")
+			}
+			total += n
+		}
+		return total, nil
+	})
+	interp.Register("bad", func(args ...interface{}) (interface{}, error) {
+		return make(chan int), nil
+	})
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`sum([1, 2, 3])`, int64(6)},
+		{`sum([1, "two"])`, "1:1: sum expects integers"},
+		{`bad()`, "1:1: cannot convert chan int to a Monkey value"},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Run(tt.input)
+
+		if expected, ok := tt.expected.(string); ok {
+			if err == nil || err.Error() != expected {
+				t.Errorf("%q: wrong error. expected=%q, got=%v", tt.input, expected, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: wrong result. expected=%#v, got=%#v", tt.input, tt.expected, result)
+		}
+	}
+
+	if _, err := New().Run(`sum([1])`); err == nil || err.Error() != "1:1: identifier not found: sum" {
+		t.Errorf("a builtin registered with one interpreter is visible to another. got=%v", err)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	interp := NewWithConfig(evaluator.Config{Builtins: evaluator.NewBuiltins()})
+	interp.Register("double", func(args ...interface{}) (interface{}, error) {
+		return args[0].(int64) * 2, nil
+	})
+
+	if result, err := interp.Run(`double(21)`); err != nil || result != int64(42) {
+		t.Errorf("wrong result. got=%#v (%v)", result, err)
+	}
+	if _, err := interp.Run(`puts("hi")`); err == nil || err.Error() != "1:1: identifier not found: puts" {
+		t.Errorf("expected puts to be unavailable. got=%v", err)
+	}
+}
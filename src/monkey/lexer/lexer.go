@@ -1,36 +1,119 @@
 package lexer
 
-import "monkey/token"
+import (
+	"fmt"
+	"monkey/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mode controls optional lexer behaviour
+type Mode uint
+
+const (
+	// ScanComments returns comments as COMMENT tokens instead of skipping them
+	ScanComments Mode = 1 << iota
+)
+
+// Error describes a problem found in the input, such as an illegal character
+type Error struct {
+	Pos token.Position
+	End token.Position
+	Msg string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
 
 // Lexer reads inputs and returns tokens
 type Lexer struct {
+	filename     string
 	input        string
-	position     int  // current position in input (points to current char)
-	readPosition int  // current read position in input (after current char)
-	ch           byte // current char under examination
+	mode         Mode
+	position     int  // current byte offset in input (points to current char)
+	readPosition int  // current read offset in input (after current char)
+	ch           rune // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char
+	errors       []Error
 }
 
 // New creates a Lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile creates a Lexer whose token positions refer to the given filename
+func NewFile(filename, input string) *Lexer {
+	l := &Lexer{
+		filename: filename,
+		input:    input,
+		line:     1,
+	}
 	l.readChar()
 	return l
 }
 
+// SetMode changes the lexer mode for the tokens that follow
+func (l *Lexer) SetMode(mode Mode) {
+	l.mode = mode
+}
+
+// Errors returns the problems found in the input so far. Each of them
+// corresponds to an ILLEGAL token.
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
 // NextToken reads the next token and advances the read positions
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	for {
+		l.skipWhitespace()
+
+		pos := l.currentPosition()
+		tok := l.readToken()
+		tok.Pos = pos
+		tok.End = l.currentPosition()
 
-	l.skipWhitespace()
+		if tok.Type == token.ILLEGAL {
+			msg := fmt.Sprintf("illegal character %q", tok.Literal)
+			if !utf8.ValidString(tok.Literal) {
+				msg = "invalid UTF-8 encoding"
+			}
+			l.addError(tok, msg)
+		}
+
+		if tok.Type == token.COMMENT && l.mode&ScanComments == 0 {
+			continue
+		}
+
+		return tok
+	}
+}
+
+// addError records an error for an ILLEGAL token, unless a more specific
+// error has already been recorded within it
+func (l *Lexer) addError(tok token.Token, msg string) {
+	if n := len(l.errors); n > 0 && l.errors[n-1].Pos.Offset >= tok.Pos.Offset {
+		return
+	}
+
+	l.errors = append(l.errors, Error{Pos: tok.Pos, End: tok.End, Msg: msg})
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
-		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.EQ, Literal: literal}
-		} else {
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken(token.EQ)
+		case '>':
+			tok = l.twoCharToken(token.ARROW)
+		default:
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case ';':
@@ -42,39 +125,130 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.twoCharToken(token.PLUS_ASSIGN)
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.twoCharToken(token.MINUS_ASSIGN)
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.NOT_EQ, Literal: literal}
+			tok = l.twoCharToken(token.NOT_EQ)
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		switch l.peekChar() {
+		case '/':
+			tok.Type = token.COMMENT
+			tok.Literal = l.readLineComment()
+			return tok
+		case '*':
+			start := l.currentPosition()
+			literal, terminated := l.readBlockComment()
+			tok.Type = token.COMMENT
+			tok.Literal = literal
+			if !terminated {
+				tok.Type = token.ILLEGAL
+				l.errors = append(l.errors, Error{
+					Pos: start,
+					End: l.currentPosition(),
+					Msg: "unterminated block comment",
+				})
+			}
+			return tok
+		case '=':
+			tok = l.twoCharToken(token.SLASH_ASSIGN)
+		default:
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		switch l.peekChar() {
+		case '*':
+			tok = l.twoCharToken(token.POWER)
+		case '=':
+			tok = l.twoCharToken(token.ASTERISK_ASSIGN)
+		default:
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken(token.LT_EQ)
+		case '<':
+			tok = l.twoCharToken(token.SHIFT_LEFT)
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken(token.GT_EQ)
+		case '>':
+			tok = l.twoCharToken(token.SHIFT_RIGHT)
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.twoCharToken(token.AND)
+		} else {
+			tok = newToken(token.BIT_AND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.twoCharToken(token.OR)
+		} else {
+			tok = newToken(token.BIT_OR, l.ch)
+		}
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '~':
+		tok = newToken(token.BIT_NOT, l.ch)
 	case '{':
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
 	case '"':
+		position := l.position
+		value, ok := l.readString()
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok.Literal = value
+		if !ok {
+			// keep the raw source, quotes included, for the ILLEGAL token
+			end := l.position + 1
+			if end > len(l.input) {
+				end = len(l.input)
+			}
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.input[position:end]
+		}
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if l.peekChar() == '.' && l.peekCharAt(2) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else if l.peekChar() == '.' {
+			tok = l.twoCharToken(token.RANGE)
+		} else if isDigit(l.peekChar()) {
+			tok.Type, tok.Literal = l.readNumber()
+			return tok
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -84,9 +258,11 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
 			return tok
+		} else if l.invalidEncoding() {
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.input[l.position:l.readPosition]
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
@@ -96,15 +272,59 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// twoCharToken consumes the current char and the next one as a single token
+func (l *Lexer) twoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	literal := string(ch) + string(l.ch)
+	return token.Token{Type: tokenType, Literal: literal}
+}
+
 func (l *Lexer) readChar() {
+	if l.readPosition > len(l.input) {
+		// already at EOF
+		return
+	}
+
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	l.position = l.readPosition
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+		l.readPosition++
+		return
 	}
 
-	l.position = l.readPosition
-	l.readPosition++
+	r, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	l.ch = r
+	l.readPosition += width
+}
+
+// invalidEncoding reports whether the current char is not valid UTF-8
+func (l *Lexer) invalidEncoding() bool {
+	return l.ch == utf8.RuneError && l.readPosition-l.position == 1
+}
+
+// nextPosition returns the position immediately after the current char
+func (l *Lexer) nextPosition() token.Position {
+	pos := l.currentPosition()
+	pos.Offset = l.readPosition
+	pos.Column++
+	return pos
+}
+
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -117,56 +337,247 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or a float, such as 42, 3.14, .5 or 1e-9
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
+	tokenType := token.TokenType(token.INT)
 
 	for isDigit(l.ch) {
 		l.readChar()
 	}
 
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || (next == '+' || next == '-') && isDigit(l.peekCharAt(2)) {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			for isDigit(l.ch) {
+				l.readChar()
+			}
+		}
+	}
+
+	return tokenType, l.input[position:l.position]
+}
+
+// readLineComment reads a // comment, up to but not including the end of line
+func (l *Lexer) readLineComment() string {
+	position := l.position
+
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+
 	return l.input[position:l.position]
 }
 
+// readBlockComment reads a /* */ comment, or the rest of the input if the
+// comment is never closed. It reports whether the closing */ was found.
+func (l *Lexer) readBlockComment() (string, bool) {
+	position := l.position
+
+	// skip the opening /*
+	l.readChar()
+	l.readChar()
+
+	for l.ch != 0 {
+		if l.ch == '*' && l.peekChar() == '/' {
+			l.readChar()
+			l.readChar()
+			return l.input[position:l.position], true
+		}
+		l.readChar()
+	}
+
+	return l.input[position:l.position], false
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
 	}
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
-	} else {
-		return l.input[l.readPosition]
+func (l *Lexer) peekChar() rune {
+	return l.peekCharAt(1)
+}
+
+// peekCharAt returns the char offset chars after the current one
+func (l *Lexer) peekCharAt(offset int) rune {
+	position := l.readPosition
+
+	for ; offset > 0; offset-- {
+		if position >= len(l.input) {
+			return 0
+		}
+
+		r, width := utf8.DecodeRuneInString(l.input[position:])
+		if offset == 1 {
+			return r
+		}
+		position += width
 	}
 
+	return 0
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+// readString reads a string literal, decoding any escape sequences in it. It
+// reports whether the string was valid; if not, an error has been recorded
+// and the lexer has moved past the closing quote, if there was one.
+func (l *Lexer) readString() (string, bool) {
+	var out strings.Builder
+
+	start := l.currentPosition()
+	valid := true
+
 	for {
 		l.readChar()
-		// note that Monkey doesn't support escape characters.
-		// We could add that logic here if we choose to.
-		if l.ch == '"' || l.ch == 0 {
-			break
+
+		switch l.ch {
+		case '"':
+			return out.String(), valid
+		case 0:
+			l.errors = append(l.errors, Error{
+				Pos: start,
+				End: l.currentPosition(),
+				Msg: "unterminated string literal",
+			})
+			return out.String(), false
+		case '\\':
+			if l.peekChar() == 0 {
+				// let the loop report the unterminated string
+				continue
+			}
+
+			escapeStart := l.currentPosition()
+			l.readChar()
+
+			r, msg := l.readEscape()
+			if msg != "" {
+				if valid {
+					l.errors = append(l.errors, Error{
+						Pos: escapeStart,
+						End: l.nextPosition(),
+						Msg: msg,
+					})
+				}
+				valid = false
+				continue
+			}
+			out.WriteRune(r)
+		default:
+			if l.invalidEncoding() {
+				if valid {
+					l.errors = append(l.errors, Error{
+						Pos: l.currentPosition(),
+						End: l.nextPosition(),
+						Msg: "invalid UTF-8 encoding in string literal",
+					})
+				}
+				valid = false
+				continue
+			}
+			out.WriteRune(l.ch)
 		}
 	}
-	return l.input[position:l.position]
+}
+
+// readEscape decodes the escape sequence starting at the current char, which
+// follows a backslash. It leaves the lexer on the last char of the sequence
+// and returns a description of the problem if the sequence is invalid.
+func (l *Lexer) readEscape() (rune, string) {
+	switch l.ch {
+	case 'n':
+		return '\n', ""
+	case 't':
+		return '\t', ""
+	case 'r':
+		return '\r', ""
+	case '\\':
+		return '\\', ""
+	case '"':
+		return '"', ""
+	case '0':
+		return 0, ""
+	case 'x':
+		var value rune
+		for i := 0; i < 2; i++ {
+			if !isHexDigit(l.peekChar()) {
+				return 0, "invalid \\x escape: expected two hex digits"
+			}
+			l.readChar()
+			value = value*16 + hexValue(l.ch)
+		}
+		return value, ""
+	case 'u':
+		if l.peekChar() != '{' {
+			return 0, "invalid \\u escape: expected \\u{XXXX}"
+		}
+		l.readChar()
+
+		var value rune
+		digits := 0
+		for isHexDigit(l.peekChar()) {
+			l.readChar()
+			digits++
+			if digits > 6 {
+				return 0, "invalid \\u escape: at most 6 hex digits are allowed"
+			}
+			value = value*16 + hexValue(l.ch)
+		}
+
+		if digits == 0 || l.peekChar() != '}' {
+			return 0, "invalid \\u escape: expected \\u{XXXX}"
+		}
+		l.readChar()
+
+		if !utf8.ValidRune(value) {
+			return 0, fmt.Sprintf("invalid \\u escape: U+%04X is not a valid code point", value)
+		}
+		return value, ""
+	default:
+		return 0, fmt.Sprintf("unknown escape sequence \\%c", l.ch)
+	}
 }
 
 // Utility functions
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{
 		Type:    tokenType,
 		Literal: string(ch),
 	}
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func hexValue(ch rune) rune {
+	switch {
+	case isDigit(ch):
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
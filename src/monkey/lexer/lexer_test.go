@@ -14,7 +14,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -149,3 +149,380 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  "foo" + x`
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{Filename: "test.mk", Offset: 0, Line: 1, Column: 1}, token.Position{Filename: "test.mk", Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 4, Line: 1, Column: 5}, token.Position{Filename: "test.mk", Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Filename: "test.mk", Offset: 6, Line: 1, Column: 7}, token.Position{Filename: "test.mk", Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Filename: "test.mk", Offset: 8, Line: 1, Column: 9}, token.Position{Filename: "test.mk", Offset: 9, Line: 1, Column: 10}},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 9, Line: 1, Column: 10}, token.Position{Filename: "test.mk", Offset: 10, Line: 1, Column: 11}},
+		{token.STRING, token.Position{Filename: "test.mk", Offset: 13, Line: 2, Column: 3}, token.Position{Filename: "test.mk", Offset: 18, Line: 2, Column: 8}},
+		{token.PLUS, token.Position{Filename: "test.mk", Offset: 19, Line: 2, Column: 9}, token.Position{Filename: "test.mk", Offset: 20, Line: 2, Column: 10}},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 21, Line: 2, Column: 11}, token.Position{Filename: "test.mk", Offset: 22, Line: 2, Column: 12}},
+		{token.EOF, token.Position{Filename: "test.mk", Offset: 22, Line: 2, Column: 12}, token.Position{Filename: "test.mk", Offset: 22, Line: 2, Column: 12}},
+	}
+
+	l := NewFile("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. Expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. Expected=%+v, got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+
+		if tok.End != tt.expectedEnd {
+			t.Fatalf("tests[%d] - end wrong. Expected=%+v, got=%+v", i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 5; // trailing comment
+/* block
+   comment */ x / 2;
+/**/`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// leading comment"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// trailing comment"},
+		{token.COMMENT, "/* block\n   comment */"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "/**/"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	l.SetMode(ScanComments)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. Expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. Expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	l = New(input)
+	for i, tt := range tests {
+		if tt.expectedType == token.COMMENT {
+			continue
+		}
+
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong without comments. Expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected lexer errors: %v", l.Errors())
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedMessage string
+	}{
+		{"1 /* never closed", "/* never closed", "unterminated block comment"},
+		{"/*/", "/*/", "unterminated block comment"},
+		{"1 @ 2", "@", "illegal character \"@\""},
+		{`"never closed`, `"never closed`, "unterminated string literal"},
+		{`"ends with \`, `"ends with \`, "unterminated string literal"},
+		{`"a\qb" 1`, `"a\qb"`, `unknown escape sequence \q`},
+		{`"\x4"`, `"\x4"`, `invalid \x escape: expected two hex digits`},
+		{`"\u0041"`, `"\u0041"`, `invalid \u escape: expected \u{XXXX}`},
+		{`"\u{41"`, `"\u{41"`, `invalid \u escape: expected \u{XXXX}`},
+		{`"\u{D800}"`, `"\u{D800}"`, `invalid \u escape: U+D800 is not a valid code point`},
+		{`"\u{1234567}"`, `"\u{1234567}"`, `invalid \u escape: at most 6 hex digits are allowed`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		var illegal token.Token
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			if tok.Type == token.ILLEGAL {
+				illegal = tok
+			}
+		}
+
+		if illegal.Literal != tt.expectedLiteral {
+			t.Errorf("%q: wrong ILLEGAL literal. want=%q, got=%q", tt.input, tt.expectedLiteral, illegal.Literal)
+		}
+
+		errors := l.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d (%v)", tt.input, len(errors), errors)
+		}
+
+		if errors[0].Msg != tt.expectedMessage {
+			t.Errorf("%q: wrong message. want=%q, got=%q", tt.input, tt.expectedMessage, errors[0].Msg)
+		}
+
+		if errors[0].Pos.Offset < illegal.Pos.Offset || errors[0].Pos.Offset >= illegal.End.Offset {
+			t.Errorf("%q: error position %s outside of ILLEGAL token", tt.input, errors[0].Pos)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"a\tb\rc"`, "a\tb\rc"},
+		{`"back\\slash"`, "back\\slash"},
+		{`"say \"hi\""`, "say \"hi\""},
+		{`"nul\0"`, "nul\x00"},
+		{`"\x41\x7a"`, "Az"},
+		{`"\u{41}\u{e9}\u{1F600}"`, "A\u00e9\U0001F600"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != token.STRING {
+			t.Fatalf("%s: tokentype wrong. expected=%q, got=%q (%v)", tt.input, token.STRING, tok.Type, l.Errors())
+		}
+
+		if tok.Literal != tt.expected {
+			t.Errorf("%s: literal wrong. expected=%q, got=%q", tt.input, tt.expected, tok.Literal)
+		}
+
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("%s: expected EOF, got=%q", tt.input, next.Type)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `42 3.14 .5 1e-9 2E+10 6e3 1.5e2 7.foo 1e x.5 123456789012345678901234567890`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "42"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, ".5"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2E+10"},
+		{token.FLOAT, "6e3"},
+		{token.FLOAT, "1.5e2"},
+		{token.INT, "7"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "foo"},
+		{token.INT, "1"},
+		{token.IDENT, "e"},
+		{token.IDENT, "x"},
+		{token.FLOAT, ".5"},
+		{token.INT, "123456789012345678901234567890"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. Expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. Expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let größe = \"héllo, 世界\";\nπ + größe"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "größe", 5},
+		{token.ASSIGN, "=", 11},
+		{token.STRING, "héllo, 世界", 13},
+		{token.SEMICOLON, ";", 24},
+		{token.IDENT, "π", 1},
+		{token.PLUS, "+", 3},
+		{token.IDENT, "größe", 5},
+		{token.EOF, "", 10},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. Expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. Expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - column wrong. Expected=%d, got=%d", i, tt.expectedColumn, tok.Pos.Column)
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"1 + \xff", "invalid UTF-8 encoding"},
+		{"\"a\xffb\"", "invalid UTF-8 encoding in string literal"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		sawIllegal := false
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			if tok.Type == token.ILLEGAL {
+				sawIllegal = true
+			}
+		}
+
+		if !sawIllegal {
+			t.Errorf("%q: expected an ILLEGAL token", tt.input)
+		}
+
+		errors := l.Errors()
+		if len(errors) != 1 || errors[0].Msg != tt.expectedMessage {
+			t.Errorf("%q: wrong errors. want=%q, got=%v", tt.input, tt.expectedMessage, errors)
+		}
+	}
+}
+
+func TestLoopKeywords(t *testing.T) {
+	input := `while for in break continue whilst match _ => try catch finally throw macro`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.IDENT, "whilst"},
+		{token.MATCH, "match"},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.TRY, "try"},
+		{token.CATCH, "catch"},
+		{token.FINALLY, "finally"},
+		{token.THROW, "throw"},
+		{token.MACRO, "macro"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. Expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. Expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := `<= >= < > % ** * && || & | ^ ~ << >> == ...x . = += -= *= /= 1..10`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LT_EQ, "<="},
+		{token.GT_EQ, ">="},
+		{token.LT, "<"},
+		{token.GT, ">"},
+		{token.PERCENT, "%"},
+		{token.POWER, "**"},
+		{token.ASTERISK, "*"},
+		{token.AND, "&&"},
+		{token.OR, "||"},
+		{token.BIT_AND, "&"},
+		{token.BIT_OR, "|"},
+		{token.BIT_XOR, "^"},
+		{token.BIT_NOT, "~"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.EQ, "=="},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "x"},
+		{token.ILLEGAL, "."},
+		{token.ASSIGN, "="},
+		{token.PLUS_ASSIGN, "+="},
+		{token.MINUS_ASSIGN, "-="},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "1"},
+		{token.RANGE, ".."},
+		{token.INT, "10"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. Expected=%q got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. Expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
@@ -4,7 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
 	"monkey/ast"
+	"monkey/token"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -13,15 +18,22 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	BIGINT_OBJ       = "BIGINT"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	RANGE_OBJ        = "RANGE"
+	QUOTE_OBJ        = "QUOTE"
+	MACRO_OBJ        = "MACRO"
 )
 
 // Object representation used in the evaluator
@@ -50,6 +62,65 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// BigInt object type, for integers that do not fit in an Integer. The
+// evaluator only creates a BigInt for values outside the int64 range, so
+// every value has a single representation.
+type BigInt struct {
+	Value *big.Int
+}
+
+// Inspect for BigInt
+func (b *BigInt) Inspect() string {
+	return b.Value.String()
+}
+
+// Type for BigInt
+func (b *BigInt) Type() ObjectType {
+	return BIGINT_OBJ
+}
+
+// HashKey for BigInt
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write(b.Value.Bytes())
+	value := h.Sum64()
+	if b.Value.Sign() < 0 {
+		value = ^value
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+// Float object type
+type Float struct {
+	Value float64
+}
+
+// Inspect for Float. The result always reads back as a float, so 2.0 is
+// shown as 2.0 rather than 2.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// Type for Float
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+// HashKey for Float
+func (f *Float) HashKey() HashKey {
+	value := f.Value
+	if value == 0 {
+		// -0.0 and 0.0 are equal, so they must hash the same
+		value = 0
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(value)}
+}
+
 // Boolean object type
 type Boolean struct {
 	Value bool
@@ -106,9 +177,59 @@ func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
 
+// Break is the signal a break statement sends to the loop around it
+type Break struct{}
+
+// Type for Break
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+// Inspect for Break
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+// Continue is the signal a continue statement sends to the loop around it
+type Continue struct{}
+
+// Type for Continue
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
+// Inspect for Continue
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+// Frame describes a function call that was in progress when an error occurred
+type Frame struct {
+	Function string         // the name the function was bound to, if any
+	Pos      token.Position // the position of the call
+	Args     string         // a summary of the arguments passed
+	Elided   int            // how many tail calls before this one were left out
+	Omitted  int            // how many calls further out were left out of the stack
+}
+
+func (f Frame) String() string {
+	s := fmt.Sprintf("%s(%s) called at %s", f.Function, f.Args, f.Pos)
+	switch {
+	case f.Elided == 1:
+		s += " (1 tail call elided)"
+	case f.Elided > 1:
+		s += fmt.Sprintf(" (%d tail calls elided)", f.Elided)
+	}
+	return s
+}
+
 // Error type
 type Error struct {
 	Message string
+	Value   Object         // the value thrown by a throw statement, if any
+	Fatal   bool           // the error ends the evaluation, and cannot be caught
+	Pos     token.Position // where the error occurred
+	Stack   []Frame        // the calls in progress, innermost first
 }
 
 // Type for Error
@@ -121,9 +242,43 @@ func (e *Error) Inspect() string {
 	return "ERROR: " + e.Message
 }
 
+// Traceback formats the error along with where it occurred and the calls
+// that led to it, innermost first. A run of calls to the same function from
+// the same place, as recursion makes, is shown by its first call.
+func (e *Error) Traceback() string {
+	var out bytes.Buffer
+
+	out.WriteString(e.Inspect())
+	if e.Pos.IsValid() {
+		out.WriteString(" (at " + e.Pos.String() + ")")
+	}
+
+	for i := 0; i < len(e.Stack); i++ {
+		frame := e.Stack[i]
+		out.WriteString("\n    in " + frame.String())
+
+		end := i + 1
+		for end < len(e.Stack) && e.Stack[end-1].Omitted == 0 &&
+			e.Stack[end].Function == frame.Function && e.Stack[end].Pos == frame.Pos {
+			end++
+		}
+		if repeated := end - i - 1; repeated > 1 {
+			fmt.Fprintf(&out, "\n    ... repeated %d more times", repeated)
+			i = end - 1
+		}
+
+		if omitted := e.Stack[i].Omitted; omitted > 0 {
+			fmt.Fprintf(&out, "\n    ... %d more calls", omitted)
+		}
+	}
+
+	return out.String()
+}
+
 // Function object type
 type Function struct {
-	Parameters []*ast.Identifier
+	Name       string // the name the function was first bound to with let, if any
+	Parameters []*ast.Parameter
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -178,7 +333,8 @@ type BuiltinFunction func(args ...Object) Object
 
 // Builtin functions wrapper
 type Builtin struct {
-	Fn BuiltinFunction
+	Name string // the name it is registered under, if any
+	Fn   BuiltinFunction
 }
 
 // Type for Builtin
@@ -255,6 +411,99 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// SortedPairs returns the pairs of the hash ordered by key, so that looping
+// over a hash is deterministic. Keys of different types are ordered by type,
+// numbers by value and other keys by how they are shown.
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return lessKey(pairs[i].Key, pairs[j].Key)
+	})
+
+	return pairs
+}
+
+func lessKey(a, b Object) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value < b.(*Integer).Value
+	case *BigInt:
+		return a.Value.Cmp(b.(*BigInt).Value) < 0
+	case *Float:
+		return a.Value < b.(*Float).Value
+	default:
+		return a.Inspect() < b.Inspect()
+	}
+}
+
+// Range is the integers from Start up to, but not including, End
+type Range struct {
+	Start int64
+	End   int64
+}
+
+// Type for Range
+func (r *Range) Type() ObjectType {
+	return RANGE_OBJ
+}
+
+// Inspect for Range
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("%d..%d", r.Start, r.End)
+}
+
+// Quote holds an unevaluated AST node, as returned by quote
+type Quote struct {
+	Node ast.Node
+}
+
+// Type for Quote
+func (q *Quote) Type() ObjectType {
+	return QUOTE_OBJ
+}
+
+// Inspect for Quote
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+// Macro object type
+type Macro struct {
+	Parameters []*ast.Parameter
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+// Type for Macro
+func (m *Macro) Type() ObjectType {
+	return MACRO_OBJ
+}
+
+// Inspect for Macro
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
 // Hashable interface is for Objects that can be Hashed, like Strings, Integers and Booleans
 type Hashable interface {
 	HashKey() HashKey
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"math/big"
+	"monkey/token"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -40,6 +44,25 @@ func TestIntegerHashKey(t *testing.T) {
 	}
 }
 
+func TestBigIntHashKey(t *testing.T) {
+	big1 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 100)}
+	big2 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 100)}
+	neg1 := &BigInt{Value: new(big.Int).Neg(big1.Value)}
+	diff1 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 101)}
+
+	if big1.HashKey() != big2.HashKey() {
+		t.Errorf("big integers with same content have different hash keys")
+	}
+
+	if big1.HashKey() == neg1.HashKey() {
+		t.Errorf("big integers with different signs have same hash keys")
+	}
+
+	if big1.HashKey() == diff1.HashKey() {
+		t.Errorf("big integers with different content have same hash keys")
+	}
+}
+
 func TestBooleanHashKey(t *testing.T) {
 	true1 := &Boolean{Value: true}
 	true2 := &Boolean{Value: true}
@@ -58,3 +81,79 @@ func TestBooleanHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	float1 := &Float{Value: 1.5}
+	float2 := &Float{Value: 1.5}
+	diff := &Float{Value: 2.5}
+	zero := &Float{Value: 0}
+	negZero := &Float{Value: -1 * zero.Value}
+	integer := &Integer{Value: 1}
+
+	if float1.HashKey() != float2.HashKey() {
+		t.Errorf("floats with same content have different hash keys")
+	}
+
+	if float1.HashKey() == diff.HashKey() {
+		t.Errorf("floats with different content have same hash keys")
+	}
+
+	if zero.HashKey() != negZero.HashKey() {
+		t.Errorf("0.0 and -0.0 have different hash keys")
+	}
+
+	if (&Float{Value: 1}).HashKey() == integer.HashKey() {
+		t.Errorf("float and integer have same hash keys")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{2, "2.0"},
+		{-0.5, "-0.5"},
+		{1e-9, "1e-09"},
+		{1e21, "1e+21"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect for %g. want=%q, got=%q", tt.value, tt.expected, f.Inspect())
+		}
+	}
+}
+
+func TestTraceback(t *testing.T) {
+	at := func(line int) token.Position { return token.Position{Line: line, Column: 1} }
+
+	err := &Error{
+		Message: "boom",
+		Pos:     at(1),
+		Stack: []Frame{
+			{Function: "f", Pos: at(2), Args: "0"},
+			{Function: "f", Pos: at(2), Args: "1"},
+			{Function: "f", Pos: at(2), Args: "2", Omitted: 5},
+			{Function: "f", Pos: at(2), Args: "8"},
+			{Function: "f", Pos: at(2), Args: "9"},
+			{Function: "f", Pos: at(3), Args: "10"},
+			{Function: "g", Pos: at(4), Args: "10"},
+		},
+	}
+
+	expected := "ERROR: boom (at 1:1)\n" +
+		"    in f(0) called at 2:1\n" +
+		"    ... repeated 2 more times\n" +
+		"    ... 5 more calls\n" +
+		"    in f(8) called at 2:1\n" +
+		"    in f(9) called at 2:1\n" +
+		"    in f(10) called at 3:1\n" +
+		"    in g(10) called at 4:1"
+
+	if err.Traceback() != expected {
+		t.Errorf("wrong traceback.\nwant:\n%s\ngot:\n%s", expected, err.Traceback())
+	}
+}
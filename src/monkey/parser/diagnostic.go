@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"monkey/token"
+	"strings"
+)
+
+// Severity describes how serious a Diagnostic is
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// Code identifies the kind of problem a Diagnostic reports
+type Code string
+
+const (
+	CodeUnexpectedToken Code = "P001" // a specific token was expected but another was found
+	CodeNoPrefixParseFn Code = "P002" // a token cannot start an expression
+	CodeInvalidInteger  Code = "P003" // an integer literal could not be parsed
+	CodeIllegalToken    Code = "P004" // the lexer could not make sense of the input
+	CodeInvalidFloat    Code = "P005" // a float literal could not be parsed
+	CodeInvalidParam    Code = "P006" // function parameters are in an invalid order
+	CodeInvalidAssign   Code = "P007" // the left side of an assignment cannot be assigned to
+	CodeOutsideLoop     Code = "P008" // break or continue is not inside a loop
+	CodeInvalidPattern  Code = "P009" // a match arm has something other than a pattern
+)
+
+// Diagnostic is a problem found in the source, along with where it was found
+type Diagnostic struct {
+	Pos      token.Position // start of the offending source range
+	End      token.Position // position immediately after the offending source range
+	Severity Severity
+	Code     Code
+	Message  string
+	Expected []token.TokenType // the token types that would have been accepted, if known
+	Actual   token.TokenType   // the token type that was found, if relevant
+	Hint     string            // an optional suggestion on how to fix the problem
+}
+
+// String formats the Diagnostic as position: message
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Message)
+}
+
+// Render writes the Diagnostic to out, followed by the offending line of
+// input with the source range underlined by carets
+func (d Diagnostic) Render(out io.Writer, input string) {
+	fmt.Fprintf(out, "%s[%s]: %s\n", d.Severity, d.Code, d.Message)
+
+	if !d.Pos.IsValid() {
+		if d.Hint != "" {
+			fmt.Fprintf(out, "  = hint: %s\n", d.Hint)
+		}
+		return
+	}
+
+	line := sourceLine(input, d.Pos.Line)
+	lineNo := fmt.Sprintf("%d", d.Pos.Line)
+	gutter := strings.Repeat(" ", len(lineNo))
+
+	fmt.Fprintf(out, "%s--> %s\n", gutter, d.Pos)
+	fmt.Fprintf(out, "%s |\n", gutter)
+	fmt.Fprintf(out, "%s | %s\n", lineNo, line)
+	fmt.Fprintf(out, "%s | %s\n", gutter, underline(line, d.Pos, d.End))
+
+	if d.Hint != "" {
+		fmt.Fprintf(out, "%s = hint: %s\n", gutter, d.Hint)
+	}
+}
+
+// RenderDiagnostics renders each of the diagnostics in turn
+func RenderDiagnostics(out io.Writer, input string, diagnostics []Diagnostic) {
+	for _, d := range diagnostics {
+		d.Render(out, input)
+	}
+}
+
+// sourceLine returns the given line (starting at 1) of input, without its
+// line terminator
+func sourceLine(input string, line int) string {
+	lines := strings.Split(input, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	return strings.TrimRight(lines[line-1], "\r")
+}
+
+// underline builds a marker line with carets beneath the columns from start
+// up to end. Tabs in the source line are kept so the carets stay aligned.
+func underline(line string, start, end token.Position) string {
+	var out strings.Builder
+
+	chars := []rune(line)
+	for i := 0; i < start.Column-1; i++ {
+		if i < len(chars) && chars[i] == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteRune(' ')
+		}
+	}
+
+	width := 1
+	if end.Line == start.Line && end.Column > start.Column {
+		width = end.Column - start.Column
+	} else if end.Line > start.Line && len(chars) >= start.Column {
+		width = len(chars) - start.Column + 1
+	}
+
+	out.WriteString(strings.Repeat("^", width))
+
+	return out.String()
+}
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"bytes"
+	"monkey/lexer"
+	"monkey/token"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedCode     Code
+		expectedPos      string
+		expectedEnd      string
+		expectedActual   token.TokenType
+		expectedExpected []token.TokenType
+	}{
+		{"let x 5;", CodeUnexpectedToken, "1:7", "1:8", token.INT, []token.TokenType{token.ASSIGN}},
+		{"let x = (1 + 2", CodeUnexpectedToken, "1:15", "1:15", token.EOF, []token.TokenType{token.RPAREN}},
+		{"\n  let y = 1;\n  ]", CodeNoPrefixParseFn, "3:3", "3:4", token.RBRACKET, nil},
+		{"0999", CodeInvalidInteger, "1:1", "1:5", token.INT, nil},
+		{"let x = 1 + /* oops", CodeIllegalToken, "1:13", "1:20", token.ILLEGAL, nil},
+		{`let s = "a\qb";`, CodeIllegalToken, "1:11", "1:13", token.ILLEGAL, nil},
+		{`let s = "abc`, CodeIllegalToken, "1:9", "1:13", token.ILLEGAL, nil},
+		{"fn(...a, b) {}", CodeInvalidParam, "1:10", "1:11", token.IDENT, nil},
+		{"fn(a = 1, b) {}", CodeInvalidParam, "1:11", "1:12", token.IDENT, nil},
+		{"1 + 2 = 3", CodeInvalidAssign, "1:1", "1:6", token.ASSIGN, nil},
+		{"break;", CodeOutsideLoop, "1:1", "1:6", token.BREAK, nil},
+		{"while (x) { fn() { continue } }", CodeOutsideLoop, "1:20", "1:28", token.CONTINUE, nil},
+		{"for (x y) {}", CodeUnexpectedToken, "1:8", "1:9", token.IDENT, []token.TokenType{token.IN}},
+		{"match (x) { a + 1 => 2 }", CodeInvalidPattern, "1:13", "1:18", "", nil},
+		{"match (x) { [...r, a] => 2 }", CodeInvalidPattern, "1:14", "1:18", "", nil},
+		{"match (x) { {k: 1} => 2 }", CodeInvalidPattern, "1:14", "1:15", "", nil},
+		{"match (x) { f(1) => 2 }", CodeInvalidPattern, "1:13", "1:17", "", nil},
+		{"let [unquote(x)] = y;", CodeInvalidPattern, "1:6", "1:16", "", nil},
+		{"fn([a, unquote(x)]) {}", CodeInvalidPattern, "1:8", "1:18", "", nil},
+		{"match (x) { unquote(y) => 1 }", CodeInvalidPattern, "1:13", "1:23", "", nil},
+		{"match (x) { 1 : 2 }", CodeUnexpectedToken, "1:15", "1:16", token.COLON, []token.TokenType{token.ARROW}},
+		{"fn(...a = 1) {}", CodeUnexpectedToken, "1:9", "1:10", token.ASSIGN, []token.TokenType{token.RPAREN}},
+		{"let [a + 1] = x;", CodeInvalidPattern, "1:6", "1:11", "", nil},
+		{"fn({k: v}) {}", CodeInvalidPattern, "1:5", "1:6", "", nil},
+		{"fn(...[a]) {}", CodeUnexpectedToken, "1:7", "1:8", token.LBRACKET, []token.TokenType{token.IDENT}},
+		{"try { 1 } 2", CodeUnexpectedToken, "1:11", "1:12", token.INT, []token.TokenType{token.CATCH, token.FINALLY}},
+		{"try { 1 } catch { 2 }", CodeUnexpectedToken, "1:17", "1:18", token.LBRACE, []token.TokenType{token.LPAREN}},
+		{"let [a] 1;", CodeUnexpectedToken, "1:9", "1:10", token.INT, []token.TokenType{token.ASSIGN}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0 {
+			t.Fatalf("%q: expected diagnostics, got none", tt.input)
+		}
+
+		d := diagnostics[0]
+		if d.Code != tt.expectedCode {
+			t.Errorf("%q: wrong code. want=%s, got=%s", tt.input, tt.expectedCode, d.Code)
+		}
+		if d.Severity != SeverityError {
+			t.Errorf("%q: wrong severity. got=%s", tt.input, d.Severity)
+		}
+		if d.Pos.String() != tt.expectedPos {
+			t.Errorf("%q: wrong Pos. want=%s, got=%s", tt.input, tt.expectedPos, d.Pos)
+		}
+		if d.End.String() != tt.expectedEnd {
+			t.Errorf("%q: wrong End. want=%s, got=%s", tt.input, tt.expectedEnd, d.End)
+		}
+		if d.Actual != tt.expectedActual {
+			t.Errorf("%q: wrong Actual. want=%s, got=%s", tt.input, tt.expectedActual, d.Actual)
+		}
+		if len(d.Expected) != len(tt.expectedExpected) {
+			t.Errorf("%q: wrong Expected. want=%v, got=%v", tt.input, tt.expectedExpected, d.Expected)
+		}
+		for i, e := range tt.expectedExpected {
+			if i < len(d.Expected) && d.Expected[i] != e {
+				t.Errorf("%q: wrong Expected. want=%v, got=%v", tt.input, tt.expectedExpected, d.Expected)
+			}
+		}
+	}
+}
+
+func TestRenderDiagnostic(t *testing.T) {
+	input := "let a = 1;\n\tlet b = (a + 2;\n"
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) == 0 {
+		t.Fatalf("expected diagnostics, got none")
+	}
+
+	var out bytes.Buffer
+	diagnostics[0].Render(&out, input)
+
+	expected := "error[P001]: expected next token to be ), got ; instead\n" +
+		" --> 2:16\n" +
+		"  |\n" +
+		"2 | \tlet b = (a + 2;\n" +
+		"  | \t              ^\n" +
+		"  = hint: insert a ) before \";\"\n"
+
+	if out.String() != expected {
+		t.Errorf("wrong rendering.\nwant:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
@@ -11,11 +12,20 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // = or +=
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
+	RANGE       // X..Y
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
+	POWER       // X ** Y
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
@@ -26,25 +36,60 @@ type (
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+
+	token.OR:          LOGICAL_OR,
+	token.AND:         LOGICAL_AND,
+	token.EQ:          EQUALS,
+	token.NOT_EQ:      EQUALS,
+	token.LT:          LESSGREATER,
+	token.GT:          LESSGREATER,
+	token.LT_EQ:       LESSGREATER,
+	token.GT_EQ:       LESSGREATER,
+	token.RANGE:       RANGE,
+	token.BIT_OR:      BIT_OR,
+	token.BIT_XOR:     BIT_XOR,
+	token.BIT_AND:     BIT_AND,
+	token.SHIFT_LEFT:  SHIFT,
+	token.SHIFT_RIGHT: SHIFT,
+	token.PLUS:        SUM,
+	token.MINUS:       SUM,
+	token.SLASH:       PRODUCT,
+	token.ASTERISK:    PRODUCT,
+	token.PERCENT:     PRODUCT,
+	token.POWER:       POWER,
+	token.LPAREN:      CALL,
+	token.LBRACKET:    INDEX,
 }
 
 // Parser parses tokens into a Program
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []Diagnostic
 
+	prevToken token.Token
 	curToken  token.Token
 	peekToken token.Token
+	pending   []token.Token // tokens pushed back by backup, read before the lexer
+
+	// panicking is set once an error has been reported in the current
+	// statement. Further errors are dropped until the parser has
+	// synchronized on the next statement, since they are almost always
+	// caused by the first one.
+	panicking bool
+	stmtStart token.Position
+
+	// loopDepth counts the loops around the current statement, within the
+	// innermost function, so break and continue can be checked
+	loopDepth int
+
+	// quoteDepth counts the calls to quote around the current expression,
+	// in which unquote may stand for a pattern
+	quoteDepth int
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -54,7 +99,7 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []Diagnostic{},
 	}
 
 	// read two tokens, so curToken and peekToken are both set
@@ -64,16 +109,22 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -84,6 +135,23 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+	p.registerInfix(token.RANGE, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
@@ -91,8 +159,29 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) nextToken() {
+	p.prevToken = p.curToken
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+
+	if len(p.pending) > 0 {
+		p.peekToken = p.pending[0]
+		p.pending = p.pending[1:]
+	} else {
+		p.peekToken = p.l.NextToken()
+	}
+
+	// comments only show up if the lexer was asked to keep them
+	for p.peekTokenIs(token.COMMENT) {
+		p.peekToken = p.l.NextToken()
+	}
+}
+
+// backup steps back a single token, so the current token becomes the peek
+// token again. It must not be called twice without calling nextToken in
+// between.
+func (p *Parser) backup() {
+	p.pending = append([]token.Token{p.peekToken}, p.pending...)
+	p.peekToken = p.curToken
+	p.curToken = p.prevToken
 }
 
 // ParseProgram parses the tokens and returns a monkey Program
@@ -113,34 +202,129 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
-// Errors returns parser errors
+// Errors returns parser errors formatted as position: message
 func (p *Parser) Errors() []string {
+	errors := []string{}
+	for _, d := range p.errors {
+		errors = append(errors, d.String())
+	}
+	return errors
+}
+
+// Diagnostics returns the problems found while parsing
+func (p *Parser) Diagnostics() []Diagnostic {
 	return p.errors
 }
 
 func (p *Parser) parseStatement() ast.Statement {
+	start := p.curToken
+	wasPanicking := p.panicking
+
+	outerStart := p.stmtStart
+	p.stmtStart = start.Pos
+	defer func() { p.stmtStart = outerStart }()
+
+	var stmt ast.Statement
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		stmt = p.parseLetStatement()
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
+	case token.WHILE:
+		stmt = p.parseWhileStatement()
+	case token.FOR:
+		stmt = p.parseForStatement()
+	case token.BREAK, token.CONTINUE:
+		stmt = p.parseLoopControlStatement()
+	case token.THROW:
+		stmt = p.parseThrowStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
+	}
+
+	if p.panicking && !wasPanicking {
+		p.synchronize()
+		p.panicking = false
+	}
+
+	if stmt == nil {
+		return &ast.BadStatement{Token: start, To: p.curToken}
 	}
+
+	return stmt
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{
-		Token: p.curToken,
+// synchronize skips tokens until the end of the current statement, so that
+// parsing can resume after an error. It stops on a semicolon, or before a
+// closing brace or a token that starts a new statement, skipping over any
+// nested blocks on the way.
+func (p *Parser) synchronize() {
+	depth := 0
+
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+
+		if depth == 0 && (p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) || isStatementKeyword(p.peekToken.Type)) {
+			return
+		}
+
+		p.nextToken()
 	}
+}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
+func isStatementKeyword(t token.TokenType) bool {
+	switch t {
+	case token.LET, token.RETURN, token.WHILE, token.FOR, token.BREAK, token.CONTINUE, token.THROW:
+		return true
+	default:
+		return false
 	}
+}
 
-	stmt.Name = &ast.Identifier{
+// isTerminator reports whether a token closes or separates the construct
+// around an expression, rather than being part of the expression itself
+func isTerminator(t token.TokenType) bool {
+	switch t {
+	case token.RPAREN, token.RBRACE, token.RBRACKET, token.SEMICOLON,
+		token.COMMA, token.COLON, token.EOF:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Parser) badExpression(tok token.Token) ast.Expression {
+	return &ast.BadExpression{Token: tok}
+}
+
+func (p *Parser) parseLetStatement() ast.Statement {
+	stmt := &ast.LetStatement{
 		Token: p.curToken,
-		Value: p.curToken.Literal,
+	}
+
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		stmt.Pattern = p.parseDestructuringPattern()
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Name = &ast.Identifier{
+			Token: p.curToken,
+			Value: p.curToken.Literal,
+		}
 	}
 
 	if !p.expectPeek(token.ASSIGN) {
@@ -158,7 +342,7 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
-func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+func (p *Parser) parseReturnStatement() ast.Statement {
 	stmt := &ast.ReturnStatement{
 		Token: p.curToken,
 	}
@@ -174,7 +358,130 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Key = stmt.Value
+		stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	return p.parseBlockStatement()
+}
+
+// parseLoopControlStatement parses a break or a continue statement
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	tok := p.curToken
+
+	if p.loopDepth == 0 {
+		msg := fmt.Sprintf("%s outside of a loop", tok.Literal)
+		d := p.newDiagnostic(tok, CodeOutsideLoop, msg)
+		d.Actual = tok.Type
+		p.addSemanticError(d)
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok}
+	}
+	return &ast.ContinueStatement{Token: tok}
+}
+
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{
+		Token: p.curToken,
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExpressionStatement() ast.Statement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
 	stmt.Expression = p.parseExpression(LOWEST)
@@ -189,8 +496,15 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
-		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
+		bad := p.curToken
+		p.noPrefixParseFnError(bad)
+
+		// leave closing delimiters for the enclosing construct to consume
+		if isTerminator(bad.Type) && bad.Pos != p.stmtStart {
+			p.backup()
+		}
+
+		return p.badExpression(bad)
 	}
 	leftExp := prefix()
 
@@ -235,7 +549,55 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+
+	d := p.newDiagnostic(p.peekToken, CodeUnexpectedToken, msg)
+	d.Expected = []token.TokenType{t}
+	d.Actual = p.peekToken.Type
+
+	switch {
+	case p.peekTokenIs(token.EOF) && isClosingDelimiter(t):
+		d.Hint = fmt.Sprintf("the input ended before a closing %s was found", t)
+	case t == token.SEMICOLON || isClosingDelimiter(t):
+		d.Hint = fmt.Sprintf("insert a %s before %q", t, p.peekToken.Literal)
+	}
+
+	p.addError(d)
+}
+
+// addError records a diagnostic, unless the parser is still recovering from
+// an earlier error in the same statement
+func (p *Parser) addError(d Diagnostic) {
+	if p.panicking {
+		return
+	}
+
+	p.panicking = true
+	p.errors = append(p.errors, d)
+}
+
+// addSemanticError records a problem in input that is syntactically well
+// formed. Parsing can carry on as normal, so unlike addError it does not
+// start panic mode.
+func (p *Parser) addSemanticError(d Diagnostic) {
+	if p.panicking {
+		return
+	}
+
+	p.errors = append(p.errors, d)
+}
+
+func (p *Parser) newDiagnostic(tok token.Token, code Code, msg string) Diagnostic {
+	return Diagnostic{
+		Pos:      tok.Pos,
+		End:      tok.End,
+		Severity: SeverityError,
+		Code:     code,
+		Message:  msg,
+	}
+}
+
+func isClosingDelimiter(t token.TokenType) bool {
+	return t == token.RPAREN || t == token.RBRACE || t == token.RBRACKET
 }
 
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
@@ -252,10 +614,49 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		return p.parseBigIntegerLiteral()
+	}
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		d := p.newDiagnostic(p.curToken, CodeInvalidInteger, msg)
+		d.Actual = p.curToken.Type
+		p.addError(d)
+		return p.badExpression(p.curToken)
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+// parseBigIntegerLiteral parses an integer literal that does not fit in an
+// int64
+func (p *Parser) parseBigIntegerLiteral() ast.Expression {
+	value, ok := new(big.Int).SetString(p.curToken.Literal, 0)
+	if !ok {
+		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
+		d := p.newDiagnostic(p.curToken, CodeInvalidInteger, msg)
+		d.Actual = p.curToken.Type
+		p.addError(d)
+		return p.badExpression(p.curToken)
+	}
+
+	return &ast.BigIntegerLiteral{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{
+		Token: p.curToken,
+	}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		d := p.newDiagnostic(p.curToken, CodeInvalidFloat, msg)
+		d.Actual = p.curToken.Type
+		p.addError(d)
+		return p.badExpression(p.curToken)
 	}
 
 	lit.Value = value
@@ -271,20 +672,51 @@ func (p *Parser) parseBoolean() ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	lparen := p.curToken
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
 
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return p.badExpression(lparen)
 	}
 
 	return exp
 }
 
-func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function found for %s", t)
-	p.errors = append(p.errors, msg)
+func (p *Parser) noPrefixParseFnError(tok token.Token) {
+	msg := fmt.Sprintf("no prefix parse function found for %s", tok.Type)
+	d := p.newDiagnostic(tok, CodeNoPrefixParseFn, msg)
+	d.Actual = tok.Type
+	d.Hint = fmt.Sprintf("%q cannot start an expression", tok.Literal)
+	if tok.Type == token.EOF {
+		d.Hint = "the input ended where an expression was expected"
+	}
+	p.addError(d)
+}
+
+// parseIllegal reports the lexer's error for an ILLEGAL token
+func (p *Parser) parseIllegal() ast.Expression {
+	msg := fmt.Sprintf("illegal token %q", p.curToken.Literal)
+	end := p.curToken.End
+
+	pos := p.curToken.Pos
+	for _, err := range p.l.Errors() {
+		if err.Pos.Offset >= p.curToken.Pos.Offset && err.Pos.Offset < p.curToken.End.Offset {
+			msg = err.Msg
+			pos = err.Pos
+			end = err.End
+			break
+		}
+	}
+
+	d := p.newDiagnostic(p.curToken, CodeIllegalToken, msg)
+	d.Pos = pos
+	d.End = end
+	d.Actual = token.ILLEGAL
+	p.addError(d)
+
+	return p.badExpression(p.curToken)
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -308,30 +740,58 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		// ** is right associative, so 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
 	return expression
 }
 
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   target,
+	}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		msg := fmt.Sprintf("cannot assign to %s", target)
+		d := p.newDiagnostic(expression.Token, CodeInvalidAssign, msg)
+		d.Pos, d.End = target.Pos(), target.End()
+		d.Actual = expression.Token.Type
+		p.addSemanticError(d)
+	}
+
+	// assignment is right associative, so a = b = 1 is a = (b = 1)
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{
 		Token: p.curToken,
 	}
 
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return p.badExpression(expression.Token)
 	}
 
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
 
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return p.badExpression(expression.Token)
 	}
 
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return p.badExpression(expression.Token)
 	}
 
 	expression.Consequence = p.parseBlockStatement()
@@ -339,8 +799,21 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		if p.peekTokenIs(token.IF) {
+			// else if: the alternative is a block holding just the nested if
+			p.nextToken()
+			tok := p.curToken
+			expression.Alternative = &ast.BlockStatement{
+				Token: tok,
+				Statements: []ast.Statement{
+					&ast.ExpressionStatement{Token: tok, Expression: p.parseIfExpression()},
+				},
+			}
+			return expression
+		}
+
 		if !p.expectPeek(token.LBRACE) {
-			return nil
+			return p.badExpression(expression.Token)
 		}
 
 		expression.Alternative = p.parseBlockStatement()
@@ -349,6 +822,203 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return p.badExpression(expression.Token)
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return p.badExpression(expression.Token)
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return p.badExpression(expression.Token)
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return p.badExpression(expression.Token)
+		}
+
+		expression.Arms = append(expression.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return p.badExpression(expression.Token)
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return p.badExpression(expression.Token)
+	}
+
+	expression.Rbrace = p.curToken
+
+	return expression
+}
+
+// parseTryExpression parses `try { } catch (e) { } finally { }`, where either
+// the catch or the finally clause may be left out, but not both
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return p.badExpression(expression.Token)
+	}
+
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LPAREN) || !p.expectPeek(token.IDENT) {
+			return p.badExpression(expression.Token)
+		}
+
+		expression.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+			return p.badExpression(expression.Token)
+		}
+
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return p.badExpression(expression.Token)
+		}
+
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		msg := fmt.Sprintf("expected next token to be %s or %s, got %s instead",
+			token.CATCH, token.FINALLY, p.peekToken.Type)
+
+		d := p.newDiagnostic(p.peekToken, CodeUnexpectedToken, msg)
+		d.Expected = []token.TokenType{token.CATCH, token.FINALLY}
+		d.Actual = p.peekToken.Type
+		p.addError(d)
+
+		return p.badExpression(expression.Token)
+	}
+
+	return expression
+}
+
+// parseMatchArm parses `pattern [if guard] => body`. The body is either an
+// expression or a block, so a hash literal body has to be in parentheses.
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{
+		Token: p.curToken,
+	}
+
+	arm.Pattern = p.parseExpression(LOWEST)
+	p.checkPattern(arm.Pattern)
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+	} else {
+		arm.Body = p.parseExpression(LOWEST)
+	}
+
+	return arm
+}
+
+// checkPattern reports anything in pattern that cannot be matched against,
+// such as a call or arithmetic. A call to unquote is allowed inside a quote,
+// as it is replaced before the pattern is used.
+func (p *Parser) checkPattern(pattern ast.Expression) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.BigIntegerLiteral, *ast.FloatLiteral,
+		*ast.StringLiteral, *ast.Boolean, *ast.BadExpression:
+		return
+
+	case *ast.CallExpression:
+		if ident, ok := pattern.Function.(*ast.Identifier); ok && ident.Value == "unquote" {
+			if p.quoteDepth == 0 {
+				p.invalidPattern(pattern, "unquote can only be used as a pattern inside quote")
+			}
+			return
+		}
+
+	case *ast.PrefixExpression:
+		switch pattern.Right.(type) {
+		case *ast.IntegerLiteral, *ast.BigIntegerLiteral, *ast.FloatLiteral:
+			if pattern.Operator == "-" {
+				return
+			}
+		}
+
+	case *ast.ArrayLiteral:
+		for i, element := range pattern.Elements {
+			spread, ok := element.(*ast.SpreadExpression)
+			if !ok {
+				p.checkPattern(element)
+				continue
+			}
+
+			if _, ok := spread.Value.(*ast.Identifier); !ok || i != len(pattern.Elements)-1 {
+				p.invalidPattern(spread, "a rest pattern must be the last element, and an identifier")
+			}
+		}
+		return
+
+	case *ast.HashLiteral:
+		for _, key := range pattern.Entries() {
+			switch key.(type) {
+			case *ast.StringLiteral, *ast.IntegerLiteral, *ast.Boolean:
+				p.checkPattern(pattern.Pairs[key])
+			default:
+				p.invalidPattern(key, fmt.Sprintf("hash pattern keys must be literals, got %s", key))
+			}
+		}
+		return
+	}
+
+	p.invalidPattern(pattern, fmt.Sprintf("%s is not a valid pattern", pattern))
+}
+
+// parseDestructuringPattern parses the array or hash pattern of a let or a
+// parameter. It stops before a following '=', which belongs to the let or the
+// default value.
+func (p *Parser) parseDestructuringPattern() ast.Expression {
+	pattern := p.parseExpression(ASSIGN)
+	p.checkPattern(pattern)
+	return pattern
+}
+
+func (p *Parser) invalidPattern(node ast.Node, msg string) {
+	d := p.newDiagnostic(p.curToken, CodeInvalidPattern, msg)
+	d.Pos, d.End = node.Pos(), node.End()
+	p.addSemanticError(d)
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{
 		Token: p.curToken,
@@ -366,6 +1036,10 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		p.nextToken()
 	}
 
+	if p.curTokenIs(token.RBRACE) {
+		block.Rbrace = p.curToken
+	}
+
 	return block
 }
 
@@ -375,54 +1049,137 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	}
 
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return p.badExpression(lit.Token)
 	}
 
 	lit.Parameters = p.parseFunctionParameters()
+	if lit.Parameters == nil {
+		return p.badExpression(lit.Token)
+	}
 
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return p.badExpression(lit.Token)
 	}
 
+	// break and continue cannot reach a loop outside the function
+	outerLoopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = outerLoopDepth
 
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return p.badExpression(lit.Token)
+	}
+
+	lit.Parameters = p.parseFunctionParameters()
+	if lit.Parameters == nil {
+		return p.badExpression(lit.Token)
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return p.badExpression(lit.Token)
+	}
+
+	outerLoopDepth := p.loopDepth
+	p.loopDepth = 0
+	lit.Body = p.parseBlockStatement()
+	p.loopDepth = outerLoopDepth
+
+	return lit
+}
+
+func (p *Parser) parseFunctionParameters() []*ast.Parameter {
+	parameters := []*ast.Parameter{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return parameters
 	}
 
-	p.nextToken()
-
-	ident := &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
+	param := p.parseFunctionParameter()
+	if param == nil {
+		return nil
 	}
 
-	identifiers = append(identifiers, ident)
+	parameters = append(parameters, param)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
 
-		ident := &ast.Identifier{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
+		param := p.parseFunctionParameter()
+		if param == nil {
+			return nil
 		}
 
-		identifiers = append(identifiers, ident)
+		p.checkParameterOrder(parameters[len(parameters)-1], param)
+		parameters = append(parameters, param)
 	}
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
-	return identifiers
+	return parameters
+}
+
+// parseFunctionParameter parses the parameter starting at the peek token:
+// a name or a pattern, optionally followed by a default value, or ... and a
+// name
+func (p *Parser) parseFunctionParameter() *ast.Parameter {
+	param := &ast.Parameter{Token: p.peekToken}
+
+	if p.peekTokenIs(token.ELLIPSIS) {
+		p.nextToken()
+		param.Rest = true
+	}
+
+	if !param.Rest && (p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE)) {
+		p.nextToken()
+		param.Pattern = p.parseDestructuringPattern()
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		param.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !param.Rest && p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		param.Default = p.parseExpression(LOWEST)
+	}
+
+	return param
+}
+
+// checkParameterOrder reports param if it may not follow prev: nothing may
+// follow the rest parameter, and once a parameter has a default value, all
+// the ones after it need one too
+func (p *Parser) checkParameterOrder(prev, param *ast.Parameter) {
+	var msg string
+
+	switch {
+	case prev.Rest:
+		msg = fmt.Sprintf("rest parameter %s must be the last parameter", prev.Name)
+	case prev.Default != nil && param.Default == nil && !param.Rest:
+		msg = fmt.Sprintf("parameter %s needs a default value, as it follows one with a default", param.Target())
+	default:
+		return
+	}
+
+	d := p.newDiagnostic(param.Token, CodeInvalidParam, msg)
+	d.End = param.End()
+	d.Actual = param.Token.Type
+	p.addSemanticError(d)
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -431,7 +1188,17 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 		Function: function,
 	}
 
+	if ident, ok := function.(*ast.Identifier); ok && ident.Value == "quote" {
+		p.quoteDepth++
+		defer func() { p.quoteDepth-- }()
+	}
+
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return p.badExpression(exp.Token)
+	}
+	exp.Rparen = p.curToken
+
 	return exp
 }
 
@@ -448,6 +1215,10 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	}
 
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return p.badExpression(array.Token)
+	}
+	array.Rbracket = p.curToken
 
 	return array
 }
@@ -461,12 +1232,12 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseElement())
 	}
 
 	if !p.expectPeek(end) {
@@ -476,6 +1247,20 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+// parseElement parses an argument of a call or an element of an array
+// literal, either of which may be spread with ...
+func (p *Parser) parseElement() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+
+	spread := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+
+	return spread
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{
 		Token: p.curToken,
@@ -486,9 +1271,11 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp.Index = p.parseExpression(LOWEST)
 
 	if !p.expectPeek(token.RBRACKET) {
-		return nil
+		return p.badExpression(exp.Token)
 	}
 
+	exp.Rbracket = p.curToken
+
 	return exp
 }
 
@@ -497,28 +1284,37 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		Token: p.curToken,
 	}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Order = []ast.Expression{}
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
-		key := p.parseExpression(LOWEST)
 
-		if !p.expectPeek(token.COLON) {
-			return nil
-		}
+		if p.curTokenIs(token.ELLIPSIS) {
+			hash.Order = append(hash.Order, p.parseElement())
+		} else {
+			key := p.parseExpression(LOWEST)
 
-		p.nextToken()
-		value := p.parseExpression(LOWEST)
-		hash.Pairs[key] = value
+			if !p.expectPeek(token.COLON) {
+				return p.badExpression(hash.Token)
+			}
+
+			p.nextToken()
+			value := p.parseExpression(LOWEST)
+			hash.Pairs[key] = value
+			hash.Order = append(hash.Order, key)
+		}
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
-			return nil
+			return p.badExpression(hash.Token)
 		}
 	}
 
 	if !p.expectPeek(token.RBRACE) {
-		return nil
+		return p.badExpression(hash.Token)
 	}
 
+	hash.Rbrace = p.curToken
+
 	return hash
 }
 
@@ -140,6 +140,76 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	tests := []string{
+		"9223372036854775808",
+		"123456789012345678901234567890",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.BigIntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.BigIntegerLiteral. got=%T", stmt.Expression)
+		}
+
+		if literal.Value.String() != input {
+			t.Errorf("literal.Value not %s. got=%s", input, literal.Value)
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{".5;", 0.5},
+		{"1e-9;", 1e-9},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -150,6 +220,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 		{"-15", "-", 15},
 		{"!true;", "!", true},
 		{"!false;", "!", false},
+		{"~5", "~", 5},
 	}
 
 	for _, tt := range prefixTests {
@@ -196,6 +267,17 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 ** 5;", 5, "**", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
+		{"true && false", true, "&&", false},
+		{"true || false", true, "||", false},
 	}
 
 	for _, tt := range infixTests {
@@ -328,6 +410,82 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2]), (b[1], (2 * ([1, 2][1]))",
 		},
+		{
+			"a || b && c == d",
+			"(a || (b && (c == d)))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a & 1 == 0",
+			"((a & 1) == 0)",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"1 << 2 + 3",
+			"(1 << (2 + 3))",
+		},
+		{
+			"a * b % c",
+			"((a * b) % c)",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"2 * 3 ** 2",
+			"(2 * (3 ** 2))",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
+		{
+			"f(...args, a + b)",
+			"f(...args, (a + b))",
+		},
+		{
+			"[1, ...xs, 2]",
+			"[1, ...xs, 2]",
+		},
+		{
+			"f(...a + b)",
+			"f(...(a + b))",
+		},
+		{
+			`{...base, "k": v, ...other}`,
+			"{...base, k:v, ...other}",
+		},
+		{
+			"x = y = 1",
+			"(x = (y = 1))",
+		},
+		{
+			"x = a || b",
+			"(x = (a || b))",
+		},
+		{
+			"a[0] += 1 * 2",
+			"((a[0] += (1 * 2))",
+		},
+		{
+			"x -= f(y *= 2)",
+			"(x -= f((y *= 2)))",
+		},
+		{
+			"h[k] /= 2",
+			"((h[k] /= 2)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -343,6 +501,170 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x) { 1 } else if (y) { 2 } else { 3 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("alternative is not 1 statements. got=%d", len(exp.Alternative.Statements))
+	}
+
+	alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("exp.Alternative.Statements[0] is not ast.ExpressionStatement. got=%T", exp.Alternative.Statements[0])
+	}
+
+	elseIf, ok := alternative.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("alternative is not ast.IfExpression. got=%T", alternative.Expression)
+	}
+
+	if !testIdentifier(t, elseIf.Condition, "y") {
+		return
+	}
+
+	if elseIf.Alternative == nil || elseIf.Alternative.String() != "3" {
+		t.Errorf("else if has wrong alternative. got=%v", elseIf.Alternative)
+	}
+
+	if exp.End().String() != "1:42" {
+		t.Errorf("wrong End. want=1:42, got=%s", exp.End())
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 1 => a, _ => b }", "match x { 1 => a, _ => b }"},
+		{"match (x) { -1 => a, }", "match x { (-1) => a }"},
+		{"match (x) { [a, ...rest] if a > 1 => rest }", "match x { [a, ...rest] if (a > 1) => rest }"},
+		{`match (x) { {"k": [v]} => v }`, "match x { {k:[v]} => v }"},
+		{"match (f(x)) { n => { let y = n; y } }", "match f(x) { n => let y = n;y }"},
+		{"match (x) { n => ({}) }", "match x { n => {} }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f(x) } catch (e) { g(e) }", "try f(x) catch (e) g(e)"},
+		{"try { f(x) } finally { done() }", "try f(x) finally done()"},
+		{"try { f(x) } catch (e) { throw e; } finally { done() }", "try f(x) catch (e) throw e; finally done()"},
+		{`let x = try { 1 } catch (e) { 2 };`, "let x = try 1 catch (e) 2;"},
+		{`throw {"message": "bad", "code": 2};`, "throw {message:bad, code:2};"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: program.Statements does not contain 1 statement. got=%d", tt.input, len(program.Statements))
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestLoopStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x += 1 }", "while (x < 10) (x += 1)"},
+		{"for (x in xs) { puts(x) }", "for (x in xs) puts(x)"},
+		{"for (k, v in h) { puts(k, v); }", "for (k, v in h) puts(k, v)"},
+		{"for (i in 0..n - 1) { continue; }", "for (i in (0 .. (n - 1))) continue;"},
+		{"while (true) { if (x) { break } }", "while true ifx break;"},
+		{"for (x in xs) { for (y in ys) { break; } continue; }", "for (x in xs) for (y in ys) break;continue;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: program.Statements does not contain 1 statement. got=%d", tt.input, len(program.Statements))
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestDestructuringParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = xs;", "let [a, b] = xs;"},
+		{"let [a, ...rest] = f(x);", "let [a, ...rest] = f(x);"},
+		{`let {"name": n, "age": a} = person;`, "let {name:n, age:a} = person;"},
+		{`let [_, {"k": [v]}] = xs;`, "let [_, {k:[v]}] = xs;"},
+		{"let [a] = [1] + [2];", "let [a] = ([1] + [2]);"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: program.Statements does not contain 1 statement. got=%d", tt.input, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("%q: statement is not *ast.LetStatement. got=%T", tt.input, program.Statements[0])
+		}
+		if stmt.Name != nil || stmt.Pattern == nil {
+			t.Errorf("%q: expected a pattern and no name. got Name=%v, Pattern=%v", tt.input, stmt.Name, stmt.Pattern)
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
 func TestStringLiteralExpression(t *testing.T) {
 	input := `"hello world";`
 
@@ -626,8 +948,8 @@ func TestFunctionLiteralParsing(t *testing.T) {
 		t.Fatalf("function literal parameters wrong. Want 2, got=%d\n", len(function.Parameters))
 	}
 
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
+	testLiteralExpression(t, function.Parameters[0].Name, "x")
+	testLiteralExpression(t, function.Parameters[1].Name, "y")
 
 	if len(function.Body.Statements) != 1 {
 		t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n", len(function.Body.Statements))
@@ -641,6 +963,53 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T",
+			stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d\n",
+			len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0].Name, "x")
+	testLiteralExpression(t, macro.Parameters[1].Name, "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n",
+			len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T",
+			macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -649,6 +1018,10 @@ func TestFunctionParameterParsing(t *testing.T) {
 		{input: "fn() {};", expectedParams: []string{}},
 		{input: "fn(x) {};", expectedParams: []string{"x"}},
 		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+		{input: "fn(x, y = 10, ...rest) {};", expectedParams: []string{"x", "y = 10", "...rest"}},
+		{input: "fn(x = 1 + 2, y = [x]) {};", expectedParams: []string{"x = (1 + 2)", "y = [x]"}},
+		{input: "fn(...args) {};", expectedParams: []string{"...args"}},
+		{input: `fn([a, b], {"k": v} = {}) {};`, expectedParams: []string{"[a, b]", "{k:v} = {}"}},
 	}
 
 	for _, tt := range tests {
@@ -665,8 +1038,10 @@ func TestFunctionParameterParsing(t *testing.T) {
 				len(tt.expectedParams), len(function.Parameters))
 		}
 
-		for i, ident := range tt.expectedParams {
-			testLiteralExpression(t, function.Parameters[i], ident)
+		for i, param := range tt.expectedParams {
+			if function.Parameters[i].String() != param {
+				t.Errorf("parameter %d wrong. want %q, got=%q", i, param, function.Parameters[i].String())
+			}
 		}
 	}
 }
@@ -943,3 +1318,96 @@ func checkParserErrors(t *testing.T, p *Parser) {
 
 	t.FailNow()
 }
+
+func TestNodePositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+		expectedEnd string
+	}{
+		{"let x = 5;", "1:1", "1:10"},
+		{"  add(1, 2)", "1:3", "1:12"},
+		{"-a * b", "1:1", "1:7"},
+		{"[1, 2][0]", "1:1", "1:10"},
+		{"{\"a\": 1}", "1:1", "1:9"},
+		{"if (x) {\n  y\n} else {\n  z\n}", "1:1", "5:2"},
+		{"fn(x) { x }", "1:1", "1:12"},
+		{"return\n  foo;", "1:1", "2:6"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+
+		stmt := program.Statements[0]
+		if stmt.Pos().String() != tt.expectedPos {
+			t.Errorf("%q: wrong Pos. want=%s, got=%s", tt.input, tt.expectedPos, stmt.Pos())
+		}
+		if stmt.End().String() != tt.expectedEnd {
+			t.Errorf("%q: wrong End. want=%s, got=%s", tt.input, tt.expectedEnd, stmt.End())
+		}
+	}
+}
+
+func TestParsingWithComments(t *testing.T) {
+	input := `// add things
+let add = fn(x, y) { /* sum */ x + y }; // done
+add(1, 2)`
+
+	for _, mode := range []lexer.Mode{0, lexer.ScanComments} {
+		l := lexer.New(input)
+		l.SetMode(mode)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != "let add = fn(x, y) (x + y);add(1, 2)" {
+			t.Errorf("wrong program. got=%q", program.String())
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors int
+		expectedString string
+	}{
+		{"let = 5; let y = 10;", 1, "<bad statement>let y = 10;"},
+		{"let x = (1 + 2; let y = 10;", 1, "let x = <bad expression>;let y = 10;"},
+		{"let x = ; let y = 10;", 1, "let x = <bad expression>;let y = 10;"},
+		{"let f = fn() { 1 + }; let y = 10;", 1, "let f = fn() (1 + <bad expression>);let y = 10;"},
+		{"if (x { 1 } let y = 10;", 1, "<bad expression>let y = 10;"},
+		{"add(1, , 2); let y = 10;", 1, "add(1, <bad expression>, 2)let y = 10;"},
+		{"fn(1, 2) { 3 }; let y = 10;", 1, "<bad expression>let y = 10;"},
+		{"let a = fn() { let = 1; let b = 2; b }; a()", 1, "let a = fn() <bad statement>let b = 2;b;a()"},
+		{"}", 1, "<bad expression>"},
+		{") ) let y = 10;", 1, "<bad expression>let y = 10;"},
+		{"let x = [1, 2", 1, "let x = <bad expression>;"},
+		{"let x = f(1, 2", 1, "let x = <bad expression>;"},
+		{"let [a, b] = [1, 2", 1, "let [a, b] = <bad expression>;"},
+		{"{1: 2", 1, "<bad expression>"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != tt.expectedErrors {
+			t.Errorf("%q: wrong number of errors. want=%d, got=%d (%q)",
+				tt.input, tt.expectedErrors, len(p.Errors()), p.Errors())
+		}
+
+		if program.String() != tt.expectedString {
+			t.Errorf("%q: wrong program. want=%q, got=%q", tt.input, tt.expectedString, program.String())
+		}
+	}
+}